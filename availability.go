@@ -0,0 +1,96 @@
+package leopard
+
+import "math/bits"
+
+// Availability is a bitmap recording which shares of a codeword
+// (orig || recovery) are present. Unlike the nil-means-missing convention,
+// it allows telling apart a lost share from a present but malformed one.
+// Indices outside [0, Len()) cause a panic, like slice indices do.
+type Availability struct {
+	n    int
+	bits []uint64
+}
+
+// NewAvailability returns an Availability for n shares with none of them
+// marked as present.
+func NewAvailability(n int) *Availability {
+	return &Availability{n: n, bits: make([]uint64, (n+63)/64)}
+}
+
+// availabilityOf returns an Availability for the concatenation of shares
+// marking all non-empty shares as present.
+func availabilityOf(shares ...[][]byte) *Availability {
+	n := 0
+	for _, s := range shares {
+		n += len(s)
+	}
+	a := NewAvailability(n)
+	i := 0
+	for _, s := range shares {
+		for _, share := range s {
+			if len(share) != 0 {
+				a.Set(i)
+			}
+			i++
+		}
+	}
+	return a
+}
+
+// Len returns the number of shares tracked.
+func (a *Availability) Len() int { return a.n }
+
+// Set marks share i as present.
+func (a *Availability) Set(i int) {
+	a.check(i)
+	a.bits[i/64] |= 1 << uint(i%64)
+}
+
+// Unset marks share i as missing.
+func (a *Availability) Unset(i int) {
+	a.check(i)
+	a.bits[i/64] &^= 1 << uint(i%64)
+}
+
+// Has reports whether share i is present.
+func (a *Availability) Has(i int) bool {
+	a.check(i)
+	return a.bits[i/64]&(1<<uint(i%64)) != 0
+}
+
+// Count returns the number of present shares.
+func (a *Availability) Count() int {
+	count := 0
+	for _, w := range a.bits {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// Missing returns the indices of all missing shares in ascending order.
+func (a *Availability) Missing() []int {
+	var missing []int
+	for i := 0; i < a.n; i++ {
+		if !a.Has(i) {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// countMissingIn returns the number of missing shares in [from, to).
+func (a *Availability) countMissingIn(from, to int) int {
+	missing := 0
+	for i := from; i < to; i++ {
+		if !a.Has(i) {
+			missing++
+		}
+	}
+	return missing
+}
+
+func (a *Availability) check(i int) {
+	if i < 0 || i >= a.n {
+		panic("leopard: share index out of range")
+	}
+}
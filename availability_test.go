@@ -0,0 +1,33 @@
+package leopard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailability(t *testing.T) {
+	a := NewAvailability(130)
+	assert.Equal(t, 130, a.Len())
+	assert.Equal(t, 0, a.Count())
+
+	for _, i := range []int{0, 63, 64, 129} {
+		a.Set(i)
+	}
+	assert.Equal(t, 4, a.Count())
+	assert.True(t, a.Has(63))
+	assert.True(t, a.Has(64))
+	assert.False(t, a.Has(65))
+
+	a.Unset(63)
+	assert.Equal(t, 3, a.Count())
+	assert.Equal(t, 127, len(a.Missing()))
+	assert.Equal(t, []int{1, 2, 3}, a.Missing()[:3])
+
+	assert.Panics(t, func() { a.Set(130) })
+	assert.Panics(t, func() { a.Has(-1) })
+
+	b := availabilityOf([][]byte{{1}, nil}, [][]byte{{}, {2}})
+	assert.Equal(t, 4, b.Len())
+	assert.Equal(t, []int{1, 2}, b.Missing())
+}
@@ -0,0 +1,204 @@
+package leopard
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/celestiaorg/go-leopard/internal/goleo"
+)
+
+// backend runs leopard's encode and decode for a fixed codeword shape.
+// Shares passed in are ShardSize bytes; the backend pads them to bufferBytes
+// if necessary. Shares returned are bufferBytes long (to be trimmed with the
+// shareLayout) and only valid until the next call.
+// A backend is not safe for concurrent use.
+type backend interface {
+	// encode computes the recovery shares for data.
+	encode(data [][]byte) ([][]byte, Leopardresult)
+	// decode recovers the original shares missing according to avail, which
+	// covers (orig || recovery). Entry i of the result holds original share i
+	// if it was missing, other entries are undefined.
+	decode(orig, recovery [][]byte, avail *Availability) ([][]byte, Leopardresult)
+	// encodeBatch computes the recovery shares for every codeword of batch
+	// and writes them trimmed to ShardSize into the matching entry of parity.
+	// On failure, it returns the index of the failed codeword, -1 otherwise.
+	encodeBatch(batch, parity [][][]byte) (int, Leopardresult)
+	// reconstructBatch recovers the shares of every codeword of batch which
+	// are missing according to the matching entry of avails. The missing
+	// entries must already hold ShardSize bytes and are written in place.
+	// On failure, it returns the index of the failed codeword, -1 otherwise.
+	reconstructBatch(batch [][][]byte, avails []*Availability) (int, Leopardresult)
+	// encodeTo and decodeTo are encode and decode, but have leopard write
+	// its results straight to the passed in bufferBytes long buffers out,
+	// which replace the first len(out) of the backend's own work buffers;
+	// the remaining ones are still used as scratch space.
+	encodeTo(data, out [][]byte) Leopardresult
+	decodeTo(orig, recovery [][]byte, avail *Availability, out [][]byte) Leopardresult
+	// close releases the memory held by the backend.
+	close()
+}
+
+// goBackend runs the pure Go port of leopard on Go allocated buffers.
+type goBackend struct {
+	origCount     int
+	recoveryCount int
+	layout        shareLayout
+
+	// zero padded copies of the shares, only allocated if padding is needed:
+	orig     [][]byte
+	recovery [][]byte
+	// per call views on the shares where missing shares are nil:
+	origIn     [][]byte
+	recoveryIn [][]byte
+
+	encodeWork [][]byte
+	decodeWork [][]byte
+
+	// backends of the additional goroutines of batch calls, created on
+	// first use:
+	workers []*goBackend
+}
+
+func newGoBackend(origCount, recoveryCount int, layout shareLayout) *goBackend {
+	bufferBytes := layout.bufferBytes
+	b := &goBackend{
+		origCount:     origCount,
+		recoveryCount: recoveryCount,
+		layout:        layout,
+		origIn:        make([][]byte, origCount),
+		recoveryIn:    make([][]byte, recoveryCount),
+		encodeWork:    allocShares(goleo.EncodeWorkCount(origCount, recoveryCount), bufferBytes),
+		decodeWork:    allocShares(goleo.DecodeWorkCount(origCount, recoveryCount), bufferBytes),
+	}
+	if layout.padded() {
+		b.orig = allocShares(origCount, bufferBytes)
+		b.recovery = allocShares(recoveryCount, bufferBytes)
+	}
+	return b
+}
+
+func (b *goBackend) encode(data [][]byte) ([][]byte, Leopardresult) {
+	return b.encodeWork[:b.recoveryCount], b.encodeTo(data, b.encodeWork)
+}
+
+func (b *goBackend) decode(orig, recovery [][]byte, avail *Availability) ([][]byte, Leopardresult) {
+	return b.decodeWork[:b.origCount], b.decodeTo(orig, recovery, avail, b.decodeWork)
+}
+
+func (b *goBackend) encodeTo(data, out [][]byte) Leopardresult {
+	in := b.inputs(b.origIn, b.orig, data, nil, 0)
+	work := append(append([][]byte(nil), out...), b.encodeWork[len(out):]...)
+	return Leopardresult(goleo.Encode(b.layout.bufferBytes, b.origCount, b.recoveryCount, in, work))
+}
+
+func (b *goBackend) decodeTo(orig, recovery [][]byte, avail *Availability, out [][]byte) Leopardresult {
+	origIn := b.inputs(b.origIn, b.orig, orig, avail, 0)
+	recoveryIn := b.inputs(b.recoveryIn, b.recovery, recovery, avail, b.origCount)
+	work := append(append([][]byte(nil), out...), b.decodeWork[len(out):]...)
+	return Leopardresult(goleo.Decode(b.layout.bufferBytes, b.origCount, b.recoveryCount, origIn, recoveryIn, work))
+}
+
+func (b *goBackend) encodeBatch(batch, parity [][][]byte) (int, Leopardresult) {
+	return b.parallel(len(batch), func(w *goBackend, i int) Leopardresult {
+		encoded, res := w.encode(batch[i])
+		if res == LeopardSuccess {
+			for j := range parity[i] {
+				w.layout.trim(parity[i][j], encoded[j])
+			}
+		}
+		return res
+	})
+}
+
+func (b *goBackend) reconstructBatch(batch [][][]byte, avails []*Availability) (int, Leopardresult) {
+	return b.parallel(len(batch), func(w *goBackend, i int) Leopardresult {
+		shards, avail := batch[i], avails[i]
+		if avail.countMissingIn(0, w.origCount) > 0 {
+			decoded, res := w.decode(shards[:w.origCount], shards[w.origCount:], avail)
+			if res != LeopardSuccess {
+				return res
+			}
+			for j := 0; j < w.origCount; j++ {
+				if !avail.Has(j) {
+					w.layout.trim(shards[j], decoded[j])
+				}
+			}
+		}
+		if avail.countMissingIn(w.origCount, len(shards)) > 0 {
+			encoded, res := w.encode(shards[:w.origCount])
+			if res != LeopardSuccess {
+				return res
+			}
+			for j := range encoded {
+				if !avail.Has(w.origCount + j) {
+					w.layout.trim(shards[w.origCount+j], encoded[j])
+				}
+			}
+		}
+		return LeopardSuccess
+	})
+}
+
+// parallel runs fn for the codewords [0, n) on up to GOMAXPROCS goroutines,
+// each with its own backend. It returns the index and result of the first
+// failed codeword.
+func (b *goBackend) parallel(n int, fn func(w *goBackend, i int) Leopardresult) (int, Leopardresult) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for len(b.workers) < workers-1 {
+		b.workers = append(b.workers, newGoBackend(b.origCount, b.recoveryCount, b.layout))
+	}
+	results := make([]Leopardresult, n)
+	var next int64 = -1
+	run := func(w *goBackend) {
+		for i := int(atomic.AddInt64(&next, 1)); i < n; i = int(atomic.AddInt64(&next, 1)) {
+			results[i] = fn(w, i)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, w := range b.workers[:workers-1] {
+		wg.Add(1)
+		go func(w *goBackend) {
+			defer wg.Done()
+			run(w)
+		}(w)
+	}
+	run(b)
+	wg.Wait()
+	for i, res := range results {
+		if res != LeopardSuccess {
+			return i, res
+		}
+	}
+	return -1, LeopardSuccess
+}
+
+func (b *goBackend) close() {
+	b.orig, b.recovery, b.encodeWork, b.decodeWork = nil, nil, nil, nil
+	b.workers = nil
+}
+
+// inputs points in at shares, which are passed to leopard as is unless they
+// need to be copied to the zero padded bufs. Shares missing according to
+// avail are set to nil; if avail is nil, all shares are present.
+// Share i is looked up at offset+i in avail.
+func (b *goBackend) inputs(in, bufs, shares [][]byte, avail *Availability, offset int) [][]byte {
+	for i, s := range shares {
+		switch {
+		case avail != nil && !avail.Has(offset+i):
+			in[i] = nil
+		case b.layout.padded():
+			b.layout.pad(bufs[i], s)
+			in[i] = bufs[i]
+		default:
+			in[i] = s
+		}
+	}
+	return in
+}
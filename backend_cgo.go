@@ -0,0 +1,230 @@
+//go:build cgo
+// +build cgo
+
+package leopard
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/leopard
+#cgo linux CFLAGS: -fopenmp
+#cgo linux LDFLAGS: -fopenmp
+#include "leopard.h"
+#ifdef _OPENMP
+#include <omp.h>
+#endif
+
+#ifdef _OPENMP
+// leo_default_threads is OpenMP's default number of threads, read before any
+// thread's setting is changed:
+static int leo_default_threads;
+
+__attribute__((constructor)) static void leo_init_default_threads(void) {
+	leo_default_threads = omp_get_max_threads();
+}
+#endif
+
+// leo_set_threads bounds the OpenMP threads of the parallel regions started
+// by the calling thread, threads <= 0 restores OpenMP's default. It has to be
+// called right before leo_encode or leo_decode within the same call into C,
+// as Go may run every call on a different thread (and the setting sticks to
+// the thread). It only has an effect if leopard was built with OpenMP as
+// well, which shares the OpenMP runtime linked here.
+static void leo_set_threads(int threads) {
+#ifdef _OPENMP
+	omp_set_num_threads(threads > 0 ? threads : leo_default_threads);
+#endif
+}
+
+static LeopardResult leo_encode_threads(int threads, uint64_t bytes, unsigned k, unsigned m,
+		unsigned work_count, void** orig, void** work) {
+	leo_set_threads(threads);
+	return leo_encode(bytes, k, m, work_count, (const void* const*)orig, work);
+}
+
+static LeopardResult leo_decode_threads(int threads, uint64_t bytes, unsigned k, unsigned m,
+		unsigned work_count, void** orig, void** recovery, void** work) {
+	leo_set_threads(threads);
+	return leo_decode(bytes, k, m, work_count, (const void* const*)orig, (const void* const*)recovery, work);
+}
+*/
+import "C"
+import (
+	"unsafe"
+
+	cleo "github.com/celestiaorg/go-leopard/leopard"
+)
+
+// cBackend runs the C library on C allocated buffers, which are allocated
+// only once and reused for every call.
+type cBackend struct {
+	origCount     int
+	recoveryCount int
+	layout        shareLayout
+	// threads is the number of OpenMP threads requested via WithThreads,
+	// 0 for the package default (see SetThreads):
+	threads int
+	// pinner pins Go allocated shares passed to leopard as is during a call:
+	pinner sharePinner
+
+	// C allocated buffers holding copies of the shares passed to leopard,
+	// allocated for the first share which can't be passed as is (see
+	// fillInputs):
+	orig     []unsafe.Pointer
+	recovery []unsafe.Pointer
+	// per call views on orig and recovery where missing shares are nil:
+	origIn     []unsafe.Pointer
+	recoveryIn []unsafe.Pointer
+
+	encodeWork []unsafe.Pointer
+	decodeWork []unsafe.Pointer
+	// Go views on the results within encodeWork and decodeWork:
+	encoded [][]byte
+	decoded [][]byte
+
+	// buffers for batch calls, allocated on first use (see growBatch):
+	// batchShares points at the shares of every codeword during a call,
+	batchShares []unsafe.Pointer
+	// batchCopies holds the C copies of the shares at the same indices,
+	// grown for the first share which can't be passed as is (see fillBatch):
+	batchCopies []unsafe.Pointer
+	batchWork   []unsafe.Pointer
+	// per thread scratch for the decoder's inputs of batchInLen pointers
+	// (see leo_reconstruct_batch):
+	batchIn    unsafe.Pointer
+	batchInLen int
+}
+
+func newCBackend(origCount, recoveryCount int, layout shareLayout, threads int) *cBackend {
+	bufferBytes := layout.bufferBytes
+	encodeWorkCount := cleo.LeoEncodeWorkCount(uint32(origCount), uint32(recoveryCount))
+	decodeWorkCount := cleo.LeoDecodeWorkCount(uint32(origCount), uint32(recoveryCount))
+	b := &cBackend{
+		origCount:     origCount,
+		recoveryCount: recoveryCount,
+		layout:        layout,
+		threads:       threads,
+		origIn:        make([]unsafe.Pointer, origCount),
+		recoveryIn:    make([]unsafe.Pointer, recoveryCount),
+		encodeWork:    mallocBuffers(int(encodeWorkCount), bufferBytes),
+		decodeWork:    mallocBuffers(int(decodeWorkCount), bufferBytes),
+	}
+	b.encoded = make([][]byte, recoveryCount)
+	for i := range b.encoded {
+		b.encoded[i] = cBytes(b.encodeWork[i], bufferBytes)
+	}
+	// leopard writes the i-th missing original share to the i-th work buffer
+	// (independent of the recovery count); the remaining work buffers are
+	// scratch space:
+	b.decoded = make([][]byte, origCount)
+	for i := range b.decoded {
+		b.decoded[i] = cBytes(b.decodeWork[i], bufferBytes)
+	}
+	return b
+}
+
+func (b *cBackend) encode(data [][]byte) ([][]byte, Leopardresult) {
+	return b.encoded, b.callEncode(data, b.encodeWork)
+}
+
+func (b *cBackend) decode(orig, recovery [][]byte, avail *Availability) ([][]byte, Leopardresult) {
+	return b.decoded, b.callDecode(orig, recovery, avail, b.decodeWork)
+}
+
+// encodeTo and decodeTo require out to be C memory (of a ShardBuffer).
+func (b *cBackend) encodeTo(data, out [][]byte) Leopardresult {
+	return b.callEncode(data, append(pointers(out), b.encodeWork[len(out):]...))
+}
+
+func (b *cBackend) decodeTo(orig, recovery [][]byte, avail *Availability, out [][]byte) Leopardresult {
+	return b.callDecode(orig, recovery, avail, append(pointers(out), b.decodeWork[len(out):]...))
+}
+
+func (b *cBackend) callEncode(data [][]byte, work []unsafe.Pointer) Leopardresult {
+	b.fillInputs(b.origIn, &b.orig, data, nil, 0)
+	calls := startCall()
+	res := C.leo_encode_threads(
+		C.int(libraryThreads(b.threads, calls)),
+		C.uint64_t(b.layout.bufferBytes),
+		C.uint(b.origCount),
+		C.uint(b.recoveryCount),
+		C.uint(len(work)),
+		&b.origIn[0],
+		&work[0])
+	endCall()
+	b.releaseInputs()
+	return Leopardresult(res)
+}
+
+func (b *cBackend) callDecode(orig, recovery [][]byte, avail *Availability, work []unsafe.Pointer) Leopardresult {
+	b.fillInputs(b.origIn, &b.orig, orig, avail, 0)
+	b.fillInputs(b.recoveryIn, &b.recovery, recovery, avail, b.origCount)
+	calls := startCall()
+	res := C.leo_decode_threads(
+		C.int(libraryThreads(b.threads, calls)),
+		C.uint64_t(b.layout.bufferBytes),
+		C.uint(b.origCount),
+		C.uint(b.recoveryCount),
+		C.uint(len(work)),
+		&b.origIn[0],
+		&b.recoveryIn[0],
+		&work[0])
+	endCall()
+	b.releaseInputs()
+	return Leopardresult(res)
+}
+
+func (b *cBackend) close() {
+	freeAll(b.orig)
+	freeAll(b.recovery)
+	freeAll(b.encodeWork)
+	freeAll(b.decodeWork)
+	freeAll(b.batchCopies)
+	freeAll(b.batchWork)
+	freeAndNil(b.batchIn)
+	b.orig, b.recovery, b.encodeWork, b.decodeWork = nil, nil, nil, nil
+	b.batchShares, b.batchCopies, b.batchWork, b.batchIn, b.batchInLen = nil, nil, nil, nil, 0
+	b.encoded, b.decoded = nil, nil
+}
+
+// fillInputs points in at the present shares, which are passed to leopard
+// as is if they need no padding and either lie within a ShardBuffer or can
+// be pinned (see sharePinner), and are copied to *bufs otherwise, which is
+// allocated on first use. Share i is looked up at offset+i in avail; if
+// avail is nil, all shares are present. Missing shares are set to nil in
+// in, as leopard expects.
+func (b *cBackend) fillInputs(in []unsafe.Pointer, bufs *[]unsafe.Pointer, shares [][]byte, avail *Availability, offset int) {
+	for i, s := range shares {
+		switch {
+		case avail != nil && !avail.Has(offset+i):
+			in[i] = nil
+		case !b.layout.padded() && (inShardMemory(s) || b.pinner.pin(s)):
+			in[i] = unsafe.Pointer(&s[0])
+		default:
+			if *bufs == nil {
+				*bufs = mallocBuffers(len(in), b.layout.bufferBytes)
+			}
+			b.layout.pad(cBytes((*bufs)[i], b.layout.bufferBytes), s)
+			in[i] = (*bufs)[i]
+		}
+	}
+}
+
+// releaseInputs unpins the shares pinned by fillInputs and drops the
+// references to them after a call.
+func (b *cBackend) releaseInputs() {
+	b.pinner.unpin()
+	for i := range b.origIn {
+		b.origIn[i] = nil
+	}
+	for i := range b.recoveryIn {
+		b.recoveryIn[i] = nil
+	}
+}
+
+// pointers returns the addresses of the C memory shares point to.
+func pointers(shares [][]byte) []unsafe.Pointer {
+	ps := make([]unsafe.Pointer, len(shares))
+	for i, s := range shares {
+		ps[i] = unsafe.Pointer(&s[0])
+	}
+	return ps
+}
@@ -0,0 +1,87 @@
+package leopard
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPureGoMatchesDefault checks that the pure Go implementation computes
+// the same recovery shares as the default backend (the C library, if built
+// with cgo) and that both can recover each other's codewords.
+func TestPureGoMatchesDefault(t *testing.T) {
+	tcs := []struct {
+		origCount, recoveryCount, shardSize int
+	}{
+		{1, 1, 64},
+		{5, 1, 128},
+		{2, 2, 64},
+		{10, 3, 192},
+		{64, 64, 64},
+		{128, 128, 64},  // largest GF(2^8) codeword
+		{129, 127, 64},  // rounds up to 384 shares, i.e. GF(2^16)
+		{200, 100, 128}, // GF(2^16)
+		{1000, 24, 64},  // many chunks of recovery size
+		{300, 300, 100}, // padded
+	}
+	for _, tc := range tcs {
+		t.Run(fmt.Sprintf("%d+%d/%d", tc.origCount, tc.recoveryCount, tc.shardSize), func(t *testing.T) {
+			def, err := NewCodec(tc.origCount, tc.recoveryCount, tc.shardSize, WithPadding())
+			require.NoError(t, err)
+			defer def.Close()
+			pure, err := NewCodec(tc.origCount, tc.recoveryCount, tc.shardSize, WithPadding(), WithPureGo())
+			require.NoError(t, err)
+			defer pure.Close()
+
+			data := allocShares(tc.origCount, tc.shardSize)
+			for _, d := range data {
+				rand.Read(d)
+			}
+			want, err := def.Encode(data)
+			require.NoError(t, err)
+			got, err := pure.Encode(data)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+
+			shards := append(deepCopy(data), got...)
+			for _, i := range rand.Perm(len(shards))[:tc.recoveryCount] {
+				shards[i] = nil
+			}
+			for _, c := range []*Codec{def, pure} {
+				recovered := make([][]byte, len(shards))
+				copy(recovered, shards)
+				require.NoError(t, c.Reconstruct(recovered))
+				assert.Equal(t, data, recovered[:tc.origCount])
+				assert.Equal(t, want, recovered[tc.origCount:])
+			}
+		})
+	}
+}
+
+func TestPureGoLargestCodewords(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large codewords in short mode")
+	}
+	for _, counts := range [][2]int{{32768, 32768}, {65535, 1}, {49152, 16384}} {
+		c, err := NewCodec(counts[0], counts[1], 64, WithPureGo())
+		require.NoError(t, err)
+		data := allocShares(counts[0], 64)
+		for _, d := range data {
+			rand.Read(d)
+		}
+		parity, err := c.Encode(data)
+		require.NoError(t, err)
+
+		orig := deepCopy(data)
+		for _, i := range rand.Perm(counts[0])[:counts[1]] {
+			orig[i] = nil
+		}
+		dec, err := c.Recover(orig, parity)
+		require.NoError(t, err)
+		assert.Equal(t, data, dec[:counts[0]])
+		require.NoError(t, c.Close())
+	}
+}
@@ -0,0 +1,139 @@
+package leopard
+
+import "fmt"
+
+// EncodeBatch computes the recovery shares for every codeword of batch, e.g.
+// all rows of a 2D square, and returns them in the same order.
+// Every codeword must consist of OrigCount shares of ShardSize bytes.
+// Unlike calling Encode once per codeword, all codewords are processed with a
+// single call into leopard, which shares its work buffers and encodes the
+// codewords in parallel.
+func (c *Codec) EncodeBatch(batch [][][]byte) ([][][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrCodecClosed
+	}
+	for j, data := range batch {
+		if err := c.checkShares(OpEncode, data, c.origCount, 0, false); err != nil {
+			return nil, fmt.Errorf("codeword %d: %w", j, err)
+		}
+	}
+	if len(batch) == 0 {
+		return nil, nil
+	}
+	parity := make([][][]byte, len(batch))
+	for j := range parity {
+		parity[j] = allocShares(c.recoveryCount, c.shardSize)
+	}
+	if j, res := c.b.encodeBatch(batch, parity); res != LeopardSuccess {
+		return nil, fmt.Errorf("codeword %d: %w", j, c.resultToErr(OpEncode, res))
+	}
+	return parity, nil
+}
+
+// DecodeBatch recovers the missing shares of every codeword of batch in
+// place like Reconstruct, e.g. for all columns of a 2D square.
+// Every codeword must consist of (orig || recovery) with missing shares
+// being nil. Unlike calling Reconstruct once per codeword, all codewords are
+// processed with a single call into leopard, which shares its work buffers
+// and decodes the codewords in parallel.
+// If any codeword can't be recovered, an error naming it is returned and
+// batch is left untouched.
+func (c *Codec) DecodeBatch(batch [][][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrCodecClosed
+	}
+	total := c.origCount + c.recoveryCount
+	var (
+		incomplete [][][]byte
+		avails     []*Availability
+		indices    []int
+	)
+	for j, shards := range batch {
+		if err := c.checkShares(OpDecode, shards, total, 0, true); err != nil {
+			return fmt.Errorf("codeword %d: %w", j, err)
+		}
+		avail := availabilityOf(shards)
+		if avail.Count() == total {
+			continue
+		}
+		if err := c.checkRecoverable(avail); err != nil {
+			return fmt.Errorf("codeword %d: %w", j, err)
+		}
+		incomplete = append(incomplete, shards)
+		avails = append(avails, avail)
+		indices = append(indices, j)
+	}
+	if len(incomplete) == 0 {
+		return nil
+	}
+
+	// keep the original views around to restore them on failure:
+	in := make([][][]byte, len(incomplete))
+	for j, shards := range incomplete {
+		in[j] = append([][]byte(nil), shards...)
+		for i := range shards {
+			if !avails[j].Has(i) {
+				shards[i] = make([]byte, c.shardSize)
+			}
+		}
+	}
+	if j, res := c.b.reconstructBatch(incomplete, avails); res != LeopardSuccess {
+		for n, shards := range incomplete {
+			copy(shards, in[n])
+		}
+		return fmt.Errorf("codeword %d: %w", indices[j], c.resultToErr(OpDecode, res))
+	}
+	return nil
+}
+
+// EncodeBatch computes len(data) recovery shares for every codeword data of
+// batch; see Codec.EncodeBatch. All codewords must have the same shape.
+func EncodeBatch(batch [][][]byte, opts ...Option) ([][][]byte, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+	origCount, bufferBytes, err := extractCounts(batch[0])
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCodec(OpEncode, int(origCount), int(origCount), int(bufferBytes), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.EncodeBatch(batch)
+}
+
+// DecodeBatch recovers the missing shares of every codeword of batch in
+// place, of which the first origCount shares are the original data; see
+// Codec.DecodeBatch. All codewords must have the same shape.
+func DecodeBatch(batch [][][]byte, origCount int, opts ...Option) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	total := len(batch[0])
+	if origCount < 0 || origCount > total {
+		return countsError(OpDecode, LeopardInvalidcounts, origCount, total-origCount,
+			"original count must be in [0, %d]", total)
+	}
+	shardSize := 0
+	for _, shards := range batch {
+		if _, size, err := extractCounts(shards); err == nil {
+			shardSize = int(size)
+			break
+		}
+	}
+	if shardSize == 0 {
+		return errAllBuffersEmpty
+	}
+	c, err := newCodec(OpDecode, origCount, total-origCount, shardSize, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.DecodeBatch(batch)
+}
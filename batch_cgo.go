@@ -0,0 +1,239 @@
+//go:build cgo
+// +build cgo
+
+package leopard
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/leopard
+#cgo linux CFLAGS: -fopenmp
+#cgo linux LDFLAGS: -fopenmp
+#include <stdlib.h>
+#include <string.h>
+#include "leopard.h"
+#ifdef _OPENMP
+#include <omp.h>
+#endif
+
+// leo_batch_openmp reports whether the batch loops below, not leopard
+// itself, run on multiple threads.
+static int leo_batch_openmp(void) {
+#ifdef _OPENMP
+	return 1;
+#else
+	return 0;
+#endif
+}
+
+static int leo_batch_thread_num(void) {
+#ifdef _OPENMP
+	return omp_get_thread_num();
+#else
+	return 0;
+#endif
+}
+
+// leo_encode_batch encodes batch codewords of k original and m recovery
+// buffers. shares holds the k+m buffers (orig || recovery) of every codeword;
+// the recovery buffers are written to. work holds a set of work_count work
+// buffers per thread. results receives the result of every codeword.
+static void leo_encode_batch(uint64_t bytes, unsigned k, unsigned m, int batch, void** shares,
+		void** work, unsigned work_count, int threads, LeopardResult* results) {
+	int b;
+#ifdef _OPENMP
+#pragma omp parallel for num_threads(threads) schedule(dynamic)
+#endif
+	for (b = 0; b < batch; ++b) {
+		void** cw = shares + (size_t)b * (k + m);
+		void** w = work + (size_t)leo_batch_thread_num() * work_count;
+		results[b] = leo_encode(bytes, k, m, leo_encode_work_count(k, m), (const void* const*)cw, w);
+		if (results[b] == Leopard_Success) {
+			for (unsigned i = 0; i < m; ++i) {
+				memcpy(cw[k + i], w[i], bytes);
+			}
+		}
+	}
+}
+
+// leo_reconstruct_batch recovers the missing buffers of batch codewords of k
+// original and m recovery buffers in place. shares holds the k+m buffers
+// (orig || recovery) of every codeword and present flags which of them are
+// present. Missing original buffers are decoded, missing recovery buffers
+// re-encoded from the recovered original buffers. work holds a set of
+// work_count work buffers per thread, enough for encoding and decoding, and
+// in k+m pointers per thread to pass the present buffers to the decoder.
+// results receives the result of every codeword.
+static void leo_reconstruct_batch(uint64_t bytes, unsigned k, unsigned m, int batch, void** shares,
+		const unsigned char* present, void** work, unsigned work_count, const void** in, int threads,
+		LeopardResult* results) {
+	int b;
+#ifdef _OPENMP
+#pragma omp parallel for num_threads(threads) schedule(dynamic)
+#endif
+	for (b = 0; b < batch; ++b) {
+		void** cw = shares + (size_t)b * (k + m);
+		const unsigned char* p = present + (size_t)b * (k + m);
+		void** w = work + (size_t)leo_batch_thread_num() * work_count;
+		const void** ins = in + (size_t)leo_batch_thread_num() * (k + m);
+		unsigned missing_orig = 0, missing_recovery = 0;
+		for (unsigned i = 0; i < k + m; ++i) {
+			ins[i] = p[i] ? cw[i] : NULL;
+			if (!p[i] && i < k) ++missing_orig;
+			if (!p[i] && i >= k) ++missing_recovery;
+		}
+		LeopardResult r = Leopard_Success;
+		if (missing_orig > 0) {
+			r = leo_decode(bytes, k, m, leo_decode_work_count(k, m), ins, ins + k, w);
+			for (unsigned i = 0; r == Leopard_Success && i < k; ++i) {
+				if (!p[i]) memcpy(cw[i], w[i], bytes);
+			}
+		}
+		if (r == Leopard_Success && missing_recovery > 0) {
+			r = leo_encode(bytes, k, m, leo_encode_work_count(k, m), (const void* const*)cw, w);
+			for (unsigned i = 0; r == Leopard_Success && i < m; ++i) {
+				if (!p[k + i]) memcpy(cw[k + i], w[i], bytes);
+			}
+		}
+		results[b] = r;
+	}
+}
+*/
+import "C"
+import "unsafe"
+
+// encodeBatch encodes all codewords with a single call into C, see
+// leo_encode_batch.
+func (b *cBackend) encodeBatch(batch, parity [][][]byte) (int, Leopardresult) {
+	calls := startCall()
+	defer endCall()
+	threads := b.growBatch(len(batch), calls)
+	k := b.origCount
+	codewords := make([][][]byte, len(batch))
+	for i := range batch {
+		codewords[i] = append(append(make([][]byte, 0, k+b.recoveryCount), batch[i]...), parity[i]...)
+	}
+	b.fillBatch(codewords, func(i, j int) bool { return j < k })
+	results := make([]C.LeopardResult, len(batch))
+	C.leo_encode_batch(C.uint64_t(b.layout.bufferBytes), C.uint(k), C.uint(b.recoveryCount), C.int(len(batch)),
+		&b.batchShares[0], &b.batchWork[0], C.uint(len(b.batchWork)/threads), C.int(threads), &results[0])
+	i, res := firstFailure(results)
+	b.releaseBatch(codewords, func(i, j int) bool { return res == LeopardSuccess && j >= k })
+	return i, res
+}
+
+// reconstructBatch recovers all codewords with a single call into C, see
+// leo_reconstruct_batch.
+func (b *cBackend) reconstructBatch(batch [][][]byte, avails []*Availability) (int, Leopardresult) {
+	calls := startCall()
+	defer endCall()
+	threads := b.growBatch(len(batch), calls)
+	total := b.origCount + b.recoveryCount
+	present := make([]byte, len(batch)*total)
+	for i := range batch {
+		for j := 0; j < total; j++ {
+			if avails[i].Has(j) {
+				present[i*total+j] = 1
+			}
+		}
+	}
+	b.fillBatch(batch, func(i, j int) bool { return avails[i].Has(j) })
+	results := make([]C.LeopardResult, len(batch))
+	C.leo_reconstruct_batch(C.uint64_t(b.layout.bufferBytes), C.uint(b.origCount), C.uint(b.recoveryCount),
+		C.int(len(batch)), &b.batchShares[0], (*C.uchar)(&present[0]),
+		&b.batchWork[0], C.uint(len(b.batchWork)/threads), (*unsafe.Pointer)(b.batchIn), C.int(threads), &results[0])
+	i, res := firstFailure(results)
+	b.releaseBatch(batch, func(i, j int) bool { return res == LeopardSuccess && !avails[i].Has(j) })
+	return i, res
+}
+
+// fillBatch points batchShares at share j of every codeword i. Like in
+// fillInputs, shares are passed to leopard as is if they need no padding and
+// either lie within a ShardBuffer or can be pinned; the others are passed as
+// the C copies at the same index of batchCopies, which are kept for later
+// calls and grown to the batch on first use. Shares for which in(i, j) holds
+// are copied into them.
+func (b *cBackend) fillBatch(codewords [][][]byte, in func(i, j int) bool) {
+	total := b.origCount + b.recoveryCount
+	for i, shares := range codewords {
+		for j, s := range shares {
+			n := i*total + j
+			if !b.layout.padded() && (inShardMemory(s) || b.pinner.pin(s)) {
+				b.batchShares[n] = unsafe.Pointer(&s[0])
+				continue
+			}
+			if len(b.batchCopies) < len(codewords)*total {
+				more := mallocBuffers(len(codewords)*total-len(b.batchCopies), b.layout.bufferBytes)
+				b.batchCopies = append(b.batchCopies, more...)
+			}
+			if in(i, j) {
+				b.layout.pad(cBytes(b.batchCopies[n], b.layout.bufferBytes), s)
+			}
+			b.batchShares[n] = b.batchCopies[n]
+		}
+	}
+}
+
+// releaseBatch copies the results leopard wrote to the C copies of the
+// shares for which out(i, j) holds back to share j of codeword i and unpins
+// the shares after a batch call.
+func (b *cBackend) releaseBatch(codewords [][][]byte, out func(i, j int) bool) {
+	total := b.origCount + b.recoveryCount
+	for i, shares := range codewords {
+		for j := range shares {
+			n := i*total + j
+			if n < len(b.batchCopies) && b.batchShares[n] == b.batchCopies[n] && out(i, j) {
+				b.layout.trim(shares[j], cBytes(b.batchCopies[n], b.layout.bufferBytes))
+			}
+		}
+	}
+	b.pinner.unpin()
+	for n := range b.batchShares {
+		b.batchShares[n] = nil
+	}
+}
+
+// growBatch makes sure batchShares holds at least count codewords and the
+// work buffers suffice for the threads to use while calls calls are running,
+// which it returns. The work buffers are kept for later batch calls; unlike
+// the shares and their copies, they don't grow with the batch.
+// The batch loop runs on the wrapper's own OpenMP threads, independent of
+// whether leopard was built with OpenMP.
+func (b *cBackend) growBatch(count, calls int) int {
+	threads := 1
+	if C.leo_batch_openmp() != 0 {
+		threads = requestedThreads(b.threads, calls)
+	}
+	if threads > count {
+		threads = count
+	}
+	total := b.origCount + b.recoveryCount
+	if len(b.batchShares) < count*total {
+		b.batchShares = make([]unsafe.Pointer, count*total)
+	}
+	// the work buffers are used for encoding and decoding:
+	workCount := len(b.encodeWork)
+	if len(b.decodeWork) > workCount {
+		workCount = len(b.decodeWork)
+	}
+	if len(b.batchWork) != threads*workCount {
+		freeAll(b.batchWork)
+		b.batchWork = mallocBuffers(threads*workCount, b.layout.bufferBytes)
+	}
+	// the decoder's inputs are C memory as leo_reconstruct_batch writes the
+	// (possibly Go) share pointers to them:
+	if b.batchInLen < threads*total {
+		freeAndNil(b.batchIn)
+		b.batchIn = mallocBuffers(1, threads*total*int(unsafe.Sizeof(b.batchIn)))[0]
+		b.batchInLen = threads * total
+	}
+	return threads
+}
+
+// firstFailure returns the index and result of the first failed codeword.
+func firstFailure(results []C.LeopardResult) (int, Leopardresult) {
+	for i, res := range results {
+		if Leopardresult(res) != LeopardSuccess {
+			return i, Leopardresult(res)
+		}
+	}
+	return -1, LeopardSuccess
+}
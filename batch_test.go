@@ -0,0 +1,162 @@
+package leopard
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeBatch(t *testing.T) {
+	const originalCount = 32
+	const recoveryCount = 16
+	const codewords = 20
+
+	for _, tc := range []struct {
+		name      string
+		shardSize int
+		opts      []Option
+	}{
+		{"default", 64, nil},
+		{"pure Go", 64, []Option{WithPureGo()}},
+		{"padding", 100, []Option{WithPadding()}},
+		{"pure Go padding", 100, []Option{WithPureGo(), WithPadding()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewCodec(originalCount, recoveryCount, tc.shardSize, tc.opts...)
+			require.NoError(t, err)
+			defer c.Close()
+
+			batch := make([][][]byte, codewords)
+			for j := range batch {
+				batch[j] = make([][]byte, originalCount)
+				for i := range batch[j] {
+					batch[j][i] = make([]byte, tc.shardSize)
+					checkedRandBytes(batch[j][i])
+				}
+			}
+			parity, err := c.EncodeBatch(batch)
+			require.NoError(t, err)
+			require.Len(t, parity, codewords)
+
+			shards := make([][][]byte, codewords)
+			want := make([][][]byte, codewords)
+			for j := range batch {
+				// must be identical to encoding every codeword by itself:
+				p, err := c.Encode(batch[j])
+				require.NoError(t, err)
+				assert.Equal(t, p, parity[j])
+
+				want[j] = append(deepCopy(batch[j]), deepCopy(parity[j])...)
+				shards[j] = append(deepCopy(batch[j]), deepCopy(parity[j])...)
+				// lose a different number of shares per codeword, none in
+				// the first one:
+				for _, i := range rand.Perm(originalCount + recoveryCount)[:j%(recoveryCount+1)] {
+					shards[j][i] = nil
+				}
+			}
+			require.NoError(t, c.DecodeBatch(shards))
+			assert.Equal(t, want, shards)
+		})
+	}
+}
+
+func TestEncodeDecodeBatchPackageLevel(t *testing.T) {
+	const originalCount = 16
+	const bufferBytes = 128
+
+	batch := make([][][]byte, 8)
+	for j := range batch {
+		batch[j] = make([][]byte, originalCount)
+		for i := range batch[j] {
+			batch[j][i] = make([]byte, bufferBytes)
+			checkedRandBytes(batch[j][i])
+		}
+	}
+	parity, err := EncodeBatch(batch)
+	require.NoError(t, err)
+
+	shards := make([][][]byte, len(batch))
+	for j := range batch {
+		want, err := Encode(batch[j])
+		require.NoError(t, err)
+		assert.Equal(t, want, parity[j])
+
+		shards[j] = append(deepCopy(batch[j]), parity[j]...)
+		for i := 0; i < originalCount; i++ {
+			shards[j][i] = nil
+		}
+	}
+	require.NoError(t, DecodeBatch(shards, originalCount))
+	for j := range batch {
+		assert.Equal(t, batch[j], shards[j][:originalCount])
+	}
+
+	parity, err = EncodeBatch(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, parity)
+}
+
+func TestBatchErrors(t *testing.T) {
+	c, err := NewCodec(4, 2, 64)
+	require.NoError(t, err)
+
+	batch := [][][]byte{allocShares(4, 64), allocShares(4, 64), allocShares(3, 64)}
+	_, err = c.EncodeBatch(batch)
+	assert.True(t, errors.Is(err, ErrInvalidCounts))
+	assert.Contains(t, err.Error(), "codeword 2")
+
+	// a codeword without enough shares fails the whole batch, which is left
+	// untouched:
+	shards := [][][]byte{
+		append(allocShares(4, 64), nil, nil),
+		{nil, nil, nil, make([]byte, 64), make([]byte, 64), make([]byte, 64)},
+	}
+	err = c.DecodeBatch(shards)
+	assert.True(t, errors.Is(err, ErrNeedMoreData))
+	assert.Contains(t, err.Error(), "codeword 1")
+	assert.Nil(t, shards[0][4])
+
+	require.NoError(t, c.Close())
+	_, err = c.EncodeBatch(batch)
+	assert.Equal(t, ErrCodecClosed, err)
+	assert.Equal(t, ErrCodecClosed, c.DecodeBatch(shards))
+}
+
+func BenchmarkEncodeBatch(b *testing.B) {
+	// one dimension of a 128x128 square of 512 byte shares:
+	const originalCount = 128
+	const bufferBytes = 512
+
+	batch := make([][][]byte, originalCount)
+	for j := range batch {
+		batch[j] = make([][]byte, originalCount)
+		for i := range batch[j] {
+			batch[j][i] = make([]byte, bufferBytes)
+			checkedRandBytes(batch[j][i])
+		}
+	}
+	c, err := NewCodec(originalCount, originalCount, bufferBytes)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	b.Run("Encode", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, data := range batch {
+				if _, err := c.Encode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("EncodeBatch", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := c.EncodeBatch(batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package leopard
+
+import "fmt"
+
+// Support is the answer to whether a capability is available, which can be
+// unknown if the library doesn't report it.
+type Support int
+
+const (
+	SupportUnknown Support = iota
+	Supported
+	Unsupported
+)
+
+func (s Support) String() string {
+	switch s {
+	case Supported:
+		return "yes"
+	case Unsupported:
+		return "no"
+	default:
+		return "unknown"
+	}
+}
+
+// CapabilityReport describes the leopard library linked into this binary and
+// the machine it runs on, see Capabilities.
+// Leopard itself only reports whether it could be initialized (InitErr); the
+// remaining fields describe the CPU and how the library was compiled, which
+// determine what leopard does but aren't read from it.
+type CapabilityReport struct {
+	// Version is the leopard API version this wrapper was written against
+	// and HeaderVersion the one of the leopard.h it was compiled with
+	// (LEO_VERSION). Init passes Version to leo_init, which fails unless the
+	// library was built with the same version.
+	Version       int
+	HeaderVersion int
+
+	// InitErr is the result of initializing leopard (see Init);
+	// if it is non-nil, leopard can't be used on this platform.
+	InitErr error
+
+	// CPUSSSE3, CPUAVX2 and CPUNEON report whether the CPU supports the
+	// SIMD instruction sets leopard has field arithmetic for. Leopard checks
+	// the CPU the same way in leo_init and falls back to portable code
+	// otherwise, but doesn't report which code it picked. NEON is mandatory
+	// on arm64, so CPUNEON is set for arm64 builds.
+	CPUSSSE3 bool
+	CPUAVX2  bool
+	CPUNEON  bool
+
+	// OpenMP reports whether leopard was built with OpenMP support, i.e.
+	// runs encode and decode on multiple threads. The library doesn't
+	// record this itself, so it is SupportUnknown unless declared with the
+	// leopard_openmp or leopard_noopenmp build tag, which make sets
+	// depending on whether the installed library uses OpenMP.
+	OpenMP Support
+	// Threads is the number of threads the C library would run an encode
+	// or decode started now on by default, see Threads.
+	Threads int
+
+	// PureGo reports whether the C library is unavailable because the
+	// package was built without cgo, i.e. the pure Go implementation is used
+	// (which does not use SIMD instructions or OpenMP).
+	PureGo bool
+
+	// FF8Tables and FF16Tables report whether the lookup tables for GF(2^8)
+	// and GF(2^16) are built. The C library builds both in leo_init, so they
+	// follow InitErr; the pure Go implementation builds each on the first use
+	// of its field.
+	FF8Tables  bool
+	FF16Tables bool
+}
+
+// Capabilities initializes leopard if that did not happen yet and reports
+// the capabilities of the linked library.
+// Unlike the encode and decode functions it never fails, so services can
+// report why leopard is unavailable instead of crashing.
+func Capabilities() CapabilityReport {
+	initErr := Init()
+	r := libraryCapabilities()
+	r.Version = version
+	r.InitErr = initErr
+	r.OpenMP = openMPSupport()
+	r.Threads = Threads()
+	return r
+}
+
+func (r CapabilityReport) String() string {
+	status := "ok"
+	if r.InitErr != nil {
+		status = r.InitErr.Error()
+	}
+	return fmt.Sprintf("leopard v%d (header v%d): %s, cpu: ssse3=%t avx2=%t neon=%t, openmp=%s threads=%d purego=%t tables: ff8=%t ff16=%t",
+		r.Version, r.HeaderVersion, status, r.CPUSSSE3, r.CPUAVX2, r.CPUNEON, r.OpenMP, r.Threads, r.PureGo,
+		r.FF8Tables, r.FF16Tables)
+}
@@ -0,0 +1,43 @@
+//go:build cgo
+// +build cgo
+
+package leopard
+
+/*
+#cgo CFLAGS: -I${SRCDIR}/leopard
+#include "leopard.h"
+
+static int leo_cpu_has(int feature) {
+#if defined(__x86_64__) || defined(__i386__)
+	__builtin_cpu_init();
+	switch (feature) {
+	case 0: return __builtin_cpu_supports("ssse3");
+	case 1: return __builtin_cpu_supports("avx2");
+	}
+#endif
+	return 0;
+}
+*/
+import "C"
+import "runtime"
+
+// libraryCapabilities reports the CPU and the leopard.h the C library is
+// used with.
+func libraryCapabilities() CapabilityReport {
+	// leo_init builds the tables of both fields:
+	initialized := Init() == nil
+	return CapabilityReport{
+		HeaderVersion: int(C.LEO_VERSION),
+		CPUSSSE3:      C.leo_cpu_has(0) != 0,
+		CPUAVX2:       C.leo_cpu_has(1) != 0,
+		CPUNEON:       runtime.GOARCH == "arm64",
+		FF8Tables:     initialized,
+		FF16Tables:    initialized,
+	}
+}
+
+// openMPSupport reports whether the C library was built with OpenMP, see
+// CapabilityReport.OpenMP.
+func openMPSupport() Support {
+	return libraryOpenMP
+}
@@ -0,0 +1,22 @@
+//go:build !cgo
+// +build !cgo
+
+package leopard
+
+import "github.com/celestiaorg/go-leopard/internal/goleo"
+
+// libraryCapabilities reports the pure Go implementation, which implements
+// the same leopard version as the wrapper.
+func libraryCapabilities() CapabilityReport {
+	return CapabilityReport{
+		HeaderVersion: version,
+		PureGo:        true,
+		FF8Tables:     goleo.Initialized8(),
+		FF16Tables:    goleo.Initialized16(),
+	}
+}
+
+// openMPSupport reports that the pure Go implementation doesn't use OpenMP.
+func openMPSupport() Support {
+	return Unsupported
+}
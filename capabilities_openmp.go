@@ -0,0 +1,7 @@
+//go:build cgo && leopard_openmp
+// +build cgo,leopard_openmp
+
+package leopard
+
+// libraryOpenMP declares that the linked C library was built with OpenMP.
+const libraryOpenMP = Supported
@@ -0,0 +1,8 @@
+//go:build cgo && !leopard_openmp && !leopard_noopenmp
+// +build cgo,!leopard_openmp,!leopard_noopenmp
+
+package leopard
+
+// libraryOpenMP leaves open whether the linked C library was built with
+// OpenMP, as neither leopard_openmp nor leopard_noopenmp is set.
+const libraryOpenMP = SupportUnknown
@@ -0,0 +1,27 @@
+package leopard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	// the pure Go implementation builds the tables of each field on first
+	// use, of GF(2^8) for 2+2 and of GF(2^16) for 300+300 shares:
+	_, err := Encode(allocShares(2, 64))
+	require.NoError(t, err)
+	_, err = Encode(allocShares(300, 64))
+	require.NoError(t, err)
+
+	caps := Capabilities()
+	assert.NoError(t, caps.InitErr)
+	assert.Equal(t, version, caps.Version)
+	assert.Equal(t, caps.Version, caps.HeaderVersion)
+	assert.True(t, caps.FF8Tables)
+	assert.True(t, caps.FF16Tables)
+	assert.Equal(t, openMPSupport(), caps.OpenMP)
+	assert.Contains(t, caps.String(), "leopard v2")
+	t.Log(caps)
+}
@@ -0,0 +1,11 @@
+//go:build cgo && go1.17
+// +build cgo,go1.17
+
+package leopard
+
+import "unsafe"
+
+// cBytes returns a Go slice backed by the C memory at p, it does not copy.
+func cBytes(p unsafe.Pointer, n int) []byte {
+	return unsafe.Slice((*byte)(p), n)
+}
@@ -0,0 +1,21 @@
+//go:build cgo && !go1.17
+// +build cgo,!go1.17
+
+package leopard
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// cBytes returns a Go slice backed by the C memory at p, it does not copy.
+// Without unsafe.Slice (Go 1.17), the slice header is set up by hand, as
+// converting p to a pointer to a fixed size array would limit n.
+func cBytes(p unsafe.Pointer, n int) []byte {
+	var b []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	h.Data = uintptr(p)
+	h.Len = n
+	h.Cap = n
+	return b
+}
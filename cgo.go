@@ -0,0 +1,100 @@
+//go:build cgo
+// +build cgo
+
+package leopard
+
+//#include <stdlib.h>
+import "C"
+import (
+	"unsafe"
+
+	cleo "github.com/celestiaorg/go-leopard/leopard"
+)
+
+// Leopardresult is the result code of a leopard operation, see LeopardError.
+type Leopardresult = cleo.Leopardresult
+
+// result codes returned by leopard:
+const (
+	LeopardSuccess        = cleo.LeopardSuccess
+	LeopardNeedmoredata   = cleo.LeopardNeedmoredata
+	LeopardToomuchdata    = cleo.LeopardToomuchdata
+	LeopardInvalidsize    = cleo.LeopardInvalidsize
+	LeopardInvalidcounts  = cleo.LeopardInvalidcounts
+	LeopardInvalidinput   = cleo.LeopardInvalidinput
+	LeopardPlatform       = cleo.LeopardPlatform
+	LeopardCallinitialize = cleo.LeopardCallinitialize
+)
+
+func resultString(errCode Leopardresult) string {
+	return cleo.LeoResultString(errCode)
+}
+
+func initLibrary() error {
+	return leopardResultToErr(OpInit, Leopardresult(cleo.LeoInit(version)))
+}
+
+// newBackend returns the C library as backend unless the pure Go
+// implementation was requested.
+func newBackend(origCount, recoveryCount int, layout shareLayout, o options) (backend, error) {
+	if o.pureGo {
+		return newGoBackend(origCount, recoveryCount, layout), nil
+	}
+	if err := Init(); err != nil {
+		return nil, err
+	}
+	return newCBackend(origCount, recoveryCount, layout, o.threads), nil
+}
+
+// wrapper around C.freeAll (can also be used in tests)
+func freeAndNil(p unsafe.Pointer) {
+	if p != nil {
+		trackFree(uintptr(p))
+		C.free(p)
+	}
+}
+
+func freeAll(ps []unsafe.Pointer) {
+	for _, p := range ps {
+		freeAndNil(p)
+	}
+}
+
+// mallocBuffers allocates count zeroed C buffers of size bytes.
+// Zeroing matters if padding is enabled: only the first shardSize bytes of
+// the input buffers are ever written to, the remainder has to stay zero.
+// Like allocShardMemory, it panics if C memory is exhausted, after freeing the
+// buffers it did allocate, rather than ever handing a NULL buffer to leopard.
+func mallocBuffers(count, size int) []unsafe.Pointer {
+	ps := make([]unsafe.Pointer, count)
+	for i := range ps {
+		ps[i] = C.calloc(1, C.size_t(size))
+		if ps[i] == nil {
+			freeAll(ps)
+			panic("leopard: out of C memory")
+		}
+		trackAlloc(uintptr(ps[i]), size)
+	}
+	return ps
+}
+
+// allocShardMemory allocates count zeroed shares of size bytes in a single
+// block of C memory for a ShardBuffer.
+func allocShardMemory(count, size int) (unsafe.Pointer, [][]byte) {
+	mem := C.calloc(C.size_t(count), C.size_t(size))
+	if mem == nil {
+		panic("leopard: out of C memory")
+	}
+	trackAlloc(uintptr(mem), count*size)
+	shares := make([][]byte, count)
+	for i := range shares {
+		shares[i] = cBytes(unsafe.Pointer(uintptr(mem)+uintptr(i*size)), size)
+	}
+	return mem, shares
+}
+
+// freeShardMemory releases memory allocated by allocShardMemory.
+func freeShardMemory(mem unsafe.Pointer) {
+	trackFree(uintptr(mem))
+	C.free(mem)
+}
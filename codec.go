@@ -0,0 +1,442 @@
+package leopard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Codec encodes and decodes codewords of a fixed shape, i.e. a fixed number of
+// original and recovery shares of a fixed size.
+// Unlike the package level functions, a Codec allocates the buffers leopard
+// works on only once and reuses them for every call.
+// A Codec is safe for concurrent use, but calls are serialized; use one Codec
+// per goroutine (or a pool of Codecs) to encode in parallel, or WithGoroutines
+// to spread single calls across several goroutines.
+// Close must be called to release the (C) memory held by the Codec.
+type Codec struct {
+	origCount     int
+	recoveryCount int
+	shardSize     int
+	// bufferBytes is the size of the buffers passed to leopard,
+	// it differs from shardSize only if padding is enabled:
+	bufferBytes int
+	layout      shareLayout
+	opts        options
+
+	mu     sync.Mutex
+	closed bool
+	b      backend
+	// backends for column stripes per worker goroutine by stripe width,
+	// created on first use (see stripeBackend):
+	stripes []map[int]backend
+}
+
+// NewCodec returns a Codec for origCount original shares and recoveryCount
+// recovery shares of shardSize bytes each.
+// recoveryCount must be in [1, origCount], origCount+recoveryCount must not
+// exceed MaxShards (with recoveryCount rounded up to the next power of two
+// unless one of the counts is 1, see EncodeWithRecovery) and shardSize must be
+// a positive multiple of 64 (unless WithPadding is passed).
+// The Codec uses the C library unless WithPureGo is passed or the package is
+// built without cgo.
+func NewCodec(origCount, recoveryCount, shardSize int, opts ...Option) (*Codec, error) {
+	return newCodec(OpNewCodec, origCount, recoveryCount, shardSize, opts)
+}
+
+// newCodec is NewCodec reporting invalid counts as failures of op, e.g. of
+// OpEncode for EncodeWithRecovery.
+func newCodec(op string, origCount, recoveryCount, shardSize int, opts []Option) (*Codec, error) {
+	o := newOptions(opts)
+	if err := checkCounts(op, origCount, recoveryCount); err != nil {
+		return nil, err
+	}
+	if err := checkShardSize(shardSize, wideSymbols(origCount, recoveryCount), o); err != nil {
+		return nil, err
+	}
+	layout := newShareLayout(origCount, recoveryCount, shardSize)
+	b, err := newBackend(origCount, recoveryCount, layout, o)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{
+		origCount:     origCount,
+		recoveryCount: recoveryCount,
+		shardSize:     shardSize,
+		bufferBytes:   layout.bufferBytes,
+		layout:        layout,
+		opts:          o,
+		b:             b,
+	}, nil
+}
+
+// OrigCount returns the number of original shares of a codeword.
+func (c *Codec) OrigCount() int { return c.origCount }
+
+// RecoveryCount returns the number of recovery shares of a codeword.
+func (c *Codec) RecoveryCount() int { return c.recoveryCount }
+
+// ShardSize returns the size of a single share in bytes.
+func (c *Codec) ShardSize() int { return c.shardSize }
+
+// Threads returns the number of threads the C library would run an encode
+// or decode of the Codec started now on, see WithThreads. It is 1 for the
+// pure Go implementation.
+func (c *Codec) Threads() int {
+	if c.opts.pureGo {
+		return 1
+	}
+	return effectiveThreads(c.opts.threads, runningCalls()+1)
+}
+
+// Close releases the (C) memory held by the Codec.
+// Calling any other method after Close returns ErrCodecClosed.
+func (c *Codec) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.b.close()
+	c.b = nil
+	for _, backends := range c.stripes {
+		for _, b := range backends {
+			b.close()
+		}
+	}
+	c.stripes = nil
+	return nil
+}
+
+// Encode computes the recovery shares for data, see EncodeWithRecovery.
+// data must consist of OrigCount shares of ShardSize bytes.
+func (c *Codec) Encode(data [][]byte) ([][]byte, error) {
+	parity := allocShares(c.recoveryCount, c.shardSize)
+	if err := c.EncodeInto(data, parity); err != nil {
+		return nil, err
+	}
+	return parity, nil
+}
+
+// EncodeInto computes the recovery shares for data and writes them into
+// parity, which must consist of RecoveryCount shares of ShardSize bytes.
+func (c *Codec) EncodeInto(data, parity [][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrCodecClosed
+	}
+	if err := c.checkShares(OpEncode, data, c.origCount, 0, false); err != nil {
+		return err
+	}
+	if err := c.checkShares(OpEncode, parity, c.recoveryCount, c.origCount, false); err != nil {
+		return err
+	}
+	if err := c.checkAliasing(OpEncode, parity, c.recoveryIndex, data); err != nil {
+		return err
+	}
+	return c.encodeInto(data, parity)
+}
+
+// Verify reports whether parity holds the recovery shares for data.
+// data must consist of OrigCount and parity of RecoveryCount shares of
+// ShardSize bytes.
+func (c *Codec) Verify(data, parity [][]byte) (bool, error) {
+	mismatches, err := c.ParityMismatches(data, parity)
+	if err != nil {
+		return false, err
+	}
+	return len(mismatches) == 0, nil
+}
+
+// ParityMismatches re-encodes data and returns the indices of the shares in
+// parity which differ from the computed recovery shares.
+func (c *Codec) ParityMismatches(data, parity [][]byte) ([]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrCodecClosed
+	}
+	if err := c.checkShares(OpEncode, data, c.origCount, 0, false); err != nil {
+		return nil, err
+	}
+	if err := c.checkShares(OpEncode, parity, c.recoveryCount, c.origCount, false); err != nil {
+		return nil, err
+	}
+	encoded, err := c.encode(data)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []int
+	trimmed := make([]byte, c.shardSize)
+	for i := range parity {
+		c.layout.trim(trimmed, encoded[i])
+		if !bytes.Equal(parity[i], trimmed) {
+			mismatches = append(mismatches, i)
+		}
+	}
+	return mismatches, nil
+}
+
+// Recover recovers missing original and recovery shares, see Recover.
+// orig must have OrigCount and recovery RecoveryCount entries; present shares
+// must be ShardSize bytes, missing ones nil.
+func (c *Codec) Recover(orig, recovery [][]byte) ([][]byte, error) {
+	out := allocShares(c.origCount+c.recoveryCount, c.shardSize)
+	if err := c.RecoverInto(orig, recovery, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RecoverInto recovers missing original and recovery shares like Recover,
+// but writes (orig || recovery) into out, which must consist of
+// OrigCount+RecoveryCount shares of ShardSize bytes.
+func (c *Codec) RecoverInto(orig, recovery, out [][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrCodecClosed
+	}
+	if err := c.checkShares(OpDecode, orig, c.origCount, 0, true); err != nil {
+		return err
+	}
+	if err := c.checkShares(OpDecode, recovery, c.recoveryCount, c.origCount, true); err != nil {
+		return err
+	}
+	if err := c.checkShares(OpDecode, out, c.origCount+c.recoveryCount, 0, false); err != nil {
+		return err
+	}
+	if err := c.checkAliasing(OpDecode, out, identity, orig, recovery); err != nil {
+		return err
+	}
+	avail := availabilityOf(orig, recovery)
+	if err := c.checkRecoverable(avail); err != nil {
+		return err
+	}
+
+	copyPresent(out, orig)
+	if countMissing(orig) > 0 {
+		if err := c.decodeOriginals(orig, recovery, avail, out[:c.origCount]); err != nil {
+			return err
+		}
+	}
+
+	if countMissing(recovery) > 0 {
+		// see decodeOriginals:
+		return c.encodeInto(out[:c.origCount], out[c.origCount:])
+	}
+	copyPresent(out[c.origCount:], recovery)
+	return nil
+}
+
+// Decode recovers missing original and recovery shares, see Decode.
+func (c *Codec) Decode(orig, recovery [][]byte) ([][]byte, error) {
+	if len(orig) != c.origCount || len(recovery) != c.recoveryCount {
+		return nil, fmt.Errorf("%w: expected %d+%d shares, got %d+%d",
+			ErrInvalidCounts, c.origCount, c.recoveryCount, len(orig), len(recovery))
+	}
+	decoded := make([][]byte, 0, len(orig)+len(recovery))
+	decoded = append(decoded, orig...)
+	decoded = append(decoded, recovery...)
+	if err := c.Reconstruct(decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// Reconstruct takes (orig || recovery) with missing shares set to nil and
+// recovers the missing shares in place: only the nil entries of shards are
+// replaced with newly allocated shares, present entries are left untouched.
+func (c *Codec) Reconstruct(shards [][]byte) error {
+	return c.reconstruct(shards, nil, false)
+}
+
+// ReconstructData is like Reconstruct, but only recovers the missing
+// original shares. Missing recovery shares are left nil.
+func (c *Codec) ReconstructData(shards [][]byte) error {
+	return c.reconstruct(shards, nil, true)
+}
+
+// ReconstructAvailable is like Reconstruct, but takes the shares which are
+// present from avail instead of treating empty entries as missing.
+// Every present share must be exactly ShardSize bytes, otherwise an error is
+// returned. The content of missing entries is ignored; they are filled in
+// place if they are ShardSize bytes and replaced with newly allocated shares
+// otherwise. On success, all shares are marked present in avail.
+func (c *Codec) ReconstructAvailable(shards [][]byte, avail *Availability) error {
+	return c.reconstruct(shards, avail, false)
+}
+
+// ReconstructDataAvailable is like ReconstructAvailable, but only recovers
+// the missing original shares.
+func (c *Codec) ReconstructDataAvailable(shards [][]byte, avail *Availability) error {
+	return c.reconstruct(shards, avail, true)
+}
+
+// reconstruct recovers the shares missing according to avail in place.
+// If avail is nil, empty shares are considered missing.
+func (c *Codec) reconstruct(shards [][]byte, avail *Availability, dataOnly bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrCodecClosed
+	}
+	if avail == nil {
+		if err := c.checkShares(OpDecode, shards, c.origCount+c.recoveryCount, 0, true); err != nil {
+			return err
+		}
+		avail = availabilityOf(shards)
+	} else {
+		if err := c.checkAvailable(shards, avail); err != nil {
+			return err
+		}
+		// missing entries of the right size are filled in place:
+		reads, writes := make([][]byte, len(shards)), make([][]byte, len(shards))
+		for i, s := range shards {
+			if avail.Has(i) {
+				reads[i] = s
+			} else if len(s) == c.shardSize {
+				writes[i] = s
+			}
+		}
+		if err := c.checkAliasing(OpDecode, writes, identity, reads); err != nil {
+			return err
+		}
+	}
+	total := c.origCount + c.recoveryCount
+	missingOrig := avail.countMissingIn(0, c.origCount)
+	missingRecovery := 0
+	if !dataOnly {
+		missingRecovery = avail.countMissingIn(c.origCount, total)
+	}
+	if missingOrig == 0 && missingRecovery == 0 {
+		return nil
+	}
+	if err := c.checkRecoverable(avail); err != nil {
+		return err
+	}
+
+	// keep the original view around as shards gets filled below:
+	in := make([][]byte, len(shards))
+	copy(in, shards)
+	outputFor := func(i int) []byte {
+		if len(shards[i]) != c.shardSize {
+			shards[i] = make([]byte, c.shardSize)
+		}
+		return shards[i]
+	}
+
+	if missingOrig > 0 {
+		for i := 0; i < c.origCount; i++ {
+			if !avail.Has(i) {
+				outputFor(i)
+			}
+		}
+		err := c.decodeOriginals(in[:c.origCount], in[c.origCount:], avail, shards[:c.origCount])
+		if err != nil {
+			copy(shards, in)
+			return err
+		}
+	}
+	if missingRecovery > 0 {
+		// see decodeOriginals:
+		encoded, err := c.encode(shards[:c.origCount])
+		if err != nil {
+			copy(shards, in)
+			return err
+		}
+		for i := 0; i < c.recoveryCount; i++ {
+			if !avail.Has(c.origCount + i) {
+				c.layout.trim(outputFor(c.origCount+i), encoded[i])
+			}
+		}
+	}
+
+	for i := 0; i < total; i++ {
+		if i < c.origCount || !dataOnly {
+			avail.Set(i)
+		}
+	}
+	return nil
+}
+
+// recoveryIndex returns the index of recovery share i within (orig || recovery).
+func (c *Codec) recoveryIndex(i int) int { return c.origCount + i }
+
+func identity(i int) int { return i }
+
+// resultToErr turns a result code returned by leopard into a *LeopardError
+// describing the Codec's shape.
+func (c *Codec) resultToErr(op string, errCode Leopardresult) error {
+	if errCode == LeopardSuccess {
+		return nil
+	}
+	return c.newError(op, errCode, -1, "")
+}
+
+func (c *Codec) newError(op string, errCode Leopardresult, shard int, format string, args ...interface{}) *LeopardError {
+	return &LeopardError{
+		Result:        errCode,
+		Op:            op,
+		OrigCount:     c.origCount,
+		RecoveryCount: c.recoveryCount,
+		BufferBytes:   c.bufferBytes,
+		Shard:         shard,
+		detail:        fmt.Sprintf(format, args...),
+	}
+}
+
+// encode calls into leopard to compute the recovery shares for data.
+// The returned shares are bufferBytes long and only valid until the next call.
+func (c *Codec) encode(data [][]byte) ([][]byte, error) {
+	if c.parallel() {
+		encoded := allocShares(c.recoveryCount, c.bufferBytes)
+		return encoded, c.encodeStripes(context.Background(), c.buffers(data), encoded)
+	}
+	encoded, res := c.b.encode(data)
+	return encoded, c.resultToErr(OpEncode, res)
+}
+
+func (c *Codec) encodeInto(data, parity [][]byte) error {
+	encoded, err := c.encode(data)
+	if err != nil {
+		return err
+	}
+	for i := range parity {
+		c.layout.trim(parity[i], encoded[i])
+	}
+	return nil
+}
+
+// decodeOriginals calls into leopard to recover the original shares missing
+// according to avail, which covers (orig || recovery).
+// Only the entries of out which correspond to missing original shares are
+// written to: leopard only recovers missing original shares, so callers
+// re-compute missing recovery shares by encoding the recovered data.
+func (c *Codec) decodeOriginals(orig, recovery [][]byte, avail *Availability, out [][]byte) error {
+	if c.parallel() {
+		return c.decodeOriginalStripes(orig, recovery, avail, out)
+	}
+	decoded, res := c.b.decode(orig, recovery, avail)
+	if err := c.resultToErr(OpDecode, res); err != nil {
+		return err
+	}
+	for i := range orig {
+		if !avail.Has(i) {
+			c.layout.trim(out[i], decoded[i])
+		}
+	}
+	return nil
+}
+
+// allocShares allocates count shares of size bytes.
+func allocShares(count, size int) [][]byte {
+	shares := make([][]byte, count)
+	for i := range shares {
+		shares[i] = make([]byte, size)
+	}
+	return shares
+}
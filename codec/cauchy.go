@@ -0,0 +1,209 @@
+package codec
+
+import (
+	"fmt"
+
+	leopard "github.com/celestiaorg/go-leopard"
+	"github.com/celestiaorg/go-leopard/gf"
+)
+
+// cauchyMaxShards is the number of distinct elements of GF(2^8), which bounds
+// the number of rows plus columns of a Cauchy matrix.
+const cauchyMaxShards = gf.Order8
+
+type cauchyCodec struct{}
+
+// NewCauchy returns a systematic Cauchy Reed-Solomon Codec over GF(2^8) for
+// codewords of up to 256 shares of any size. Recovery share j is the sum of
+// the original shares i multiplied by 1/(x_j + y_i) with x_j = origCount+j
+// and y_i = i. For the small codewords it supports it avoids leopard's FFTs
+// and padding to 64 bytes, but its recovery shares differ from leopard's.
+// It returns the same errors as leopard (e.g. leopard.ErrNeedMoreData).
+func NewCauchy() Codec {
+	return cauchyCodec{}
+}
+
+func (cauchyCodec) MaxShards() int { return cauchyMaxShards }
+
+func (cauchyCodec) ValidateShardSize(size int) error {
+	if size <= 0 {
+		return leopard.ErrInvalidSize
+	}
+	return nil
+}
+
+func (c cauchyCodec) Encode(data [][]byte, recoveryCount int) ([][]byte, error) {
+	if err := c.checkCounts(len(data), recoveryCount); err != nil {
+		return nil, err
+	}
+	size, err := c.shardSize(data, false)
+	if err != nil {
+		return nil, err
+	}
+	parity := make([][]byte, recoveryCount)
+	for j := range parity {
+		parity[j] = make([]byte, size)
+		c.encodeRow(parity[j], data, j)
+	}
+	return parity, nil
+}
+
+func (c cauchyCodec) Decode(orig, recovery [][]byte) ([][]byte, error) {
+	shards := make([][]byte, 0, len(orig)+len(recovery))
+	shards = append(shards, orig...)
+	shards = append(shards, recovery...)
+	if err := c.Reconstruct(shards, len(orig)); err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
+
+func (c cauchyCodec) Reconstruct(shards [][]byte, origCount int) error {
+	if origCount < 0 || origCount > len(shards) {
+		return leopard.ErrInvalidCounts
+	}
+	recoveryCount := len(shards) - origCount
+	if err := c.checkCounts(origCount, recoveryCount); err != nil {
+		return err
+	}
+	size, err := c.shardSize(shards, true)
+	if err != nil {
+		return err
+	}
+
+	// any origCount present shares determine the original ones:
+	present := make([]int, 0, origCount)
+	missingOrig := false
+	for i, s := range shards {
+		if len(s) == 0 {
+			missingOrig = missingOrig || i < origCount
+			continue
+		}
+		if len(present) < origCount {
+			present = append(present, i)
+		}
+	}
+	if len(present) < origCount {
+		return fmt.Errorf("%w: %d shares present, at least %d needed", leopard.ErrNeedMoreData, len(present), origCount)
+	}
+
+	if missingOrig {
+		// row r of m maps the original shares to the share present[r]:
+		m := make([][]gf.GF8, origCount)
+		for r, idx := range present {
+			m[r] = make([]gf.GF8, origCount)
+			if idx < origCount {
+				m[r][idx] = 1
+				continue
+			}
+			for i := range m[r] {
+				m[r][i] = c.coefficient(origCount, idx-origCount, i)
+			}
+		}
+		inv := invert(m)
+		recovered := make(map[int][]byte)
+		for i := 0; i < origCount; i++ {
+			if len(shards[i]) != 0 {
+				continue
+			}
+			s := make([]byte, size)
+			for r, idx := range present {
+				gf.MulAddSlice8(s, shards[idx], inv[i][r])
+			}
+			recovered[i] = s
+		}
+		// only fill in shards once all reads of the present shares are done:
+		for i, s := range recovered {
+			shards[i] = s
+		}
+	}
+	for j := 0; j < recoveryCount; j++ {
+		if len(shards[origCount+j]) == 0 {
+			s := make([]byte, size)
+			c.encodeRow(s, shards[:origCount], j)
+			shards[origCount+j] = s
+		}
+	}
+	return nil
+}
+
+// encodeRow computes recovery share j of data into dst.
+func (c cauchyCodec) encodeRow(dst []byte, data [][]byte, j int) {
+	for i, d := range data {
+		gf.MulAddSlice8(dst, d, c.coefficient(len(data), j, i))
+	}
+}
+
+// coefficient returns the entry of the Cauchy matrix data share i is
+// multiplied by for recovery share j.
+func (cauchyCodec) coefficient(origCount, j, i int) gf.GF8 {
+	x, y := gf.GF8(origCount+j), gf.GF8(i)
+	return x.Add(y).Inverse()
+}
+
+func (cauchyCodec) checkCounts(origCount, recoveryCount int) error {
+	if origCount < 1 || recoveryCount < 1 || recoveryCount > origCount {
+		return leopard.ErrInvalidCounts
+	}
+	if origCount+recoveryCount > cauchyMaxShards {
+		return leopard.ErrTooMuchData
+	}
+	return nil
+}
+
+// shardSize returns the size of the shares, which must all be the same.
+// If allowMissing is set, empty shares are accepted as missing.
+func (cauchyCodec) shardSize(shares [][]byte, allowMissing bool) (int, error) {
+	size := 0
+	for i, s := range shares {
+		switch {
+		case len(s) == 0 && allowMissing:
+		case len(s) == 0:
+			return 0, fmt.Errorf("%w: share %d is empty", leopard.ErrInvalidInput, i)
+		case size == 0:
+			size = len(s)
+		case len(s) != size:
+			return 0, fmt.Errorf("%w: share %d has %d bytes, expected %d", leopard.ErrInvalidInput, i, len(s), size)
+		}
+	}
+	if size == 0 {
+		return 0, fmt.Errorf("%w: all shares are empty", leopard.ErrInvalidInput)
+	}
+	return size, nil
+}
+
+// invert returns the inverse of the square matrix m, which must be
+// invertible (as every square submatrix of a Cauchy matrix stacked on the
+// identity is). m is modified.
+func invert(m [][]gf.GF8) [][]gf.GF8 {
+	n := len(m)
+	inv := make([][]gf.GF8, n)
+	for i := range inv {
+		inv[i] = make([]gf.GF8, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for m[pivot][col] == 0 {
+			pivot++
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		scale := m[col][col].Inverse()
+		for k := 0; k < n; k++ {
+			m[col][k] = m[col][k].Mul(scale)
+			inv[col][k] = inv[col][k].Mul(scale)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || m[row][col] == 0 {
+				continue
+			}
+			f := m[row][col]
+			for k := 0; k < n; k++ {
+				m[row][k] = m[row][k].Add(f.Mul(m[col][k]))
+				inv[row][k] = inv[row][k].Add(f.Mul(inv[col][k]))
+			}
+		}
+	}
+	return inv
+}
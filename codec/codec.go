@@ -0,0 +1,83 @@
+// Package codec provides erasure codes behind a common Codec interface and a
+// registry to pick one by name, e.g. from a configuration file:
+//
+//	c, err := codec.Get(cfg.Codec) // "leopard", "purego" or "cauchy"
+//	parity, err := c.Encode(data, len(data))
+//
+// Codecs of different names generally compute different recovery shares, so
+// all parties of a protocol must use the same one.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Codec is an erasure code for codewords of original and recovery shares of
+// equal size. A Codec is safe for concurrent use.
+type Codec interface {
+	// Encode computes recoveryCount recovery shares for the equally sized
+	// shares of data. Any recoveryCount shares of (data || recovery) can be
+	// lost and the data can still be recovered.
+	Encode(data [][]byte, recoveryCount int) ([][]byte, error)
+	// Decode recovers missing original and recovery shares, which have to
+	// be nil, and returns (orig || recovery) with all shares present; the
+	// passed in shares are returned as is.
+	Decode(orig, recovery [][]byte) ([][]byte, error)
+	// Reconstruct recovers the missing (nil) shares of (orig || recovery)
+	// in place, of which the first origCount shares are the original data.
+	Reconstruct(shards [][]byte, origCount int) error
+	// MaxShards returns the maximum number of original plus recovery shares
+	// of a codeword.
+	MaxShards() int
+	// ValidateShardSize returns an error if shares of size bytes can't be
+	// encoded.
+	ValidateShardSize(size int) error
+}
+
+// ErrUnknownCodec is returned by Get for names no Codec is registered for.
+var ErrUnknownCodec = errors.New("unknown codec")
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Codec)
+)
+
+// Register makes c available by name. It panics if c is nil or a Codec is
+// already registered under name.
+func Register(name string, c Codec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if c == nil {
+		panic("codec: Register of nil codec " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("codec: Register called twice for " + name)
+	}
+	registry[name] = c
+}
+
+// Get returns the Codec registered under name.
+func Get(name string) (Codec, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCodec, name)
+	}
+	return c, nil
+}
+
+// Names returns the sorted names of all registered Codecs.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
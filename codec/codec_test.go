@@ -0,0 +1,163 @@
+package codec_test
+
+import (
+	"crypto/rand"
+	"errors"
+	mrand "math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	leopard "github.com/celestiaorg/go-leopard"
+	"github.com/celestiaorg/go-leopard/codec"
+)
+
+func TestRegistry(t *testing.T) {
+	assert.Equal(t, []string{codec.Cauchy, codec.Leopard, codec.PureGo}, codec.Names())
+
+	_, err := codec.Get("reed-solomon")
+	assert.True(t, errors.Is(err, codec.ErrUnknownCodec))
+
+	codec.Register("leopard-padded", codec.NewLeopard(leopard.WithPadding()))
+	c, err := codec.Get("leopard-padded")
+	require.NoError(t, err)
+	assert.NoError(t, c.ValidateShardSize(100))
+	// odd sizes can't be padded in GF(2^16), e.g. for 300+100 shares:
+	assert.Equal(t, leopard.ErrInvalidSize, c.ValidateShardSize(101))
+	_, err = c.Encode(randShares(300, 101), 100)
+	assert.Equal(t, leopard.ErrInvalidSize, err)
+	assert.Panics(t, func() { codec.Register("leopard-padded", c) })
+}
+
+func TestCodecs(t *testing.T) {
+	for _, name := range []string{codec.Leopard, codec.PureGo, codec.Cauchy} {
+		t.Run(name, func(t *testing.T) {
+			c, err := codec.Get(name)
+			require.NoError(t, err)
+
+			for _, shape := range [][2]int{{1, 1}, {4, 1}, {16, 16}, {100, 28}} {
+				origCount, recoveryCount := shape[0], shape[1]
+				data := randShares(origCount, 64)
+				parity, err := c.Encode(data, recoveryCount)
+				require.NoError(t, err)
+				require.Len(t, parity, recoveryCount)
+
+				shards := append(copyShares(data), copyShares(parity)...)
+				for _, i := range mrand.Perm(origCount + recoveryCount)[:recoveryCount] {
+					shards[i] = nil
+				}
+				decoded, err := c.Decode(shards[:origCount], shards[origCount:])
+				require.NoError(t, err)
+				assert.Equal(t, append(copyShares(data), parity...), decoded)
+
+				require.NoError(t, c.Reconstruct(shards, origCount))
+				assert.Equal(t, decoded, shards)
+
+				// one share too many lost:
+				if origCount > 1 {
+					for i := 0; i <= recoveryCount; i++ {
+						shards[i] = nil
+					}
+					err = c.Reconstruct(shards, origCount)
+					assert.True(t, errors.Is(err, leopard.ErrNeedMoreData), err)
+				}
+			}
+
+			assert.Error(t, c.ValidateShardSize(0))
+			assert.NoError(t, c.ValidateShardSize(64))
+			_, err = c.Encode(randShares(c.MaxShards()/2+1, 64), c.MaxShards()/2)
+			assert.True(t, errors.Is(err, leopard.ErrTooMuchData), err)
+		})
+	}
+}
+
+func TestLeopardBackendsAgree(t *testing.T) {
+	cgo, err := codec.Get(codec.Leopard)
+	require.NoError(t, err)
+	pureGo, err := codec.Get(codec.PureGo)
+	require.NoError(t, err)
+
+	data := randShares(200, 128)
+	want, err := cgo.Encode(data, 100)
+	require.NoError(t, err)
+	got, err := pureGo.Encode(data, 100)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	assert.Equal(t, leopard.ErrInvalidSize, cgo.ValidateShardSize(100))
+}
+
+func TestLeopardConcurrent(t *testing.T) {
+	c, err := codec.Get(codec.Leopard)
+	require.NoError(t, err)
+	assert.Equal(t, leopard.MaxShards, c.MaxShards())
+
+	data := randShares(64, 128)
+	want, err := leopard.EncodeWithRecovery(data, 32)
+	require.NoError(t, err)
+	// the goroutines share the pooled Codecs of two shapes:
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				recoveryCount := 32 >> (g % 2)
+				parity, err := c.Encode(data, recoveryCount)
+				if err != nil {
+					errs <- err
+					return
+				}
+				shards := append(copyShares(data), parity...)
+				shards[0], shards[len(shards)-1] = nil, nil
+				if err := c.Reconstruct(shards, len(data)); err != nil {
+					errs <- err
+					return
+				}
+				if recoveryCount == 32 && !assert.Equal(t, want, shards[len(data):]) {
+					return
+				}
+				assert.Equal(t, data, shards[:len(data)])
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestCauchyAnySize(t *testing.T) {
+	c := codec.NewCauchy()
+	assert.Equal(t, 256, c.MaxShards())
+	data := randShares(3, 5)
+	parity, err := c.Encode(data, 2)
+	require.NoError(t, err)
+	decoded, err := c.Decode([][]byte{nil, data[1], nil}, parity)
+	require.NoError(t, err)
+	assert.Equal(t, data, decoded[:3])
+
+	_, err = c.Encode([][]byte{data[0], data[1][:4]}, 1)
+	assert.True(t, errors.Is(err, leopard.ErrInvalidInput), err)
+}
+
+func randShares(count, size int) [][]byte {
+	shares := make([][]byte, count)
+	for i := range shares {
+		shares[i] = make([]byte, size)
+		_, _ = rand.Read(shares[i])
+	}
+	return shares
+}
+
+func copyShares(shares [][]byte) [][]byte {
+	c := make([][]byte, len(shares))
+	for i, s := range shares {
+		c[i] = append([]byte(nil), s...)
+	}
+	return c
+}
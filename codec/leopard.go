@@ -0,0 +1,147 @@
+package codec
+
+import (
+	"runtime"
+	"sync"
+
+	leopard "github.com/celestiaorg/go-leopard"
+)
+
+// names of the Codecs registered by this package:
+const (
+	// Leopard is leopard using the C library, or the pure Go
+	// implementation if built without cgo.
+	Leopard = "leopard"
+	// PureGo is leopard using the pure Go implementation, which computes
+	// the same recovery shares as Leopard.
+	PureGo = "purego"
+	// Cauchy is a Cauchy Reed-Solomon code over GF(2^8) for codewords of up
+	// to 256 shares, see NewCauchy.
+	Cauchy = "cauchy"
+)
+
+func init() {
+	Register(Leopard, NewLeopard())
+	Register(PureGo, NewLeopard(leopard.WithPureGo()))
+	Register(Cauchy, NewCauchy())
+}
+
+// leopardCodec keeps a pool of leopard.Codecs per codeword shape, so that
+// calls of the same shape reuse the (C) buffers leopard works on instead of
+// allocating and freeing them every time.
+type leopardCodec struct {
+	opts  []leopard.Option
+	pools sync.Map // shape -> *sync.Pool of *leopard.Codec
+}
+
+// shape is the shape of a leopard.Codec.
+type shape struct {
+	origCount, recoveryCount, shardSize int
+}
+
+// NewLeopard returns a Codec running leopard with opts, e.g. to register a
+// variant with padding enabled.
+func NewLeopard(opts ...leopard.Option) Codec {
+	return &leopardCodec{opts: opts}
+}
+
+func (l *leopardCodec) Encode(data [][]byte, recoveryCount int) ([][]byte, error) {
+	s := shape{len(data), recoveryCount, shareSize(data)}
+	if s.shardSize == 0 {
+		// leave reporting the malformed input to leopard:
+		return leopard.EncodeWithRecovery(data, recoveryCount, l.opts...)
+	}
+	c, err := l.get(s)
+	if err != nil {
+		return nil, err
+	}
+	defer l.put(s, c)
+	return c.Encode(data)
+}
+
+func (l *leopardCodec) Decode(orig, recovery [][]byte) ([][]byte, error) {
+	s := decodeShape(orig, recovery)
+	if s.shardSize == 0 {
+		return leopard.Decode(orig, recovery, l.opts...)
+	}
+	c, err := l.get(s)
+	if err != nil {
+		return nil, err
+	}
+	defer l.put(s, c)
+	return c.Decode(orig, recovery)
+}
+
+func (l *leopardCodec) Reconstruct(shards [][]byte, origCount int) error {
+	if origCount < 0 || origCount > len(shards) {
+		return leopard.Reconstruct(shards, origCount, l.opts...)
+	}
+	orig, recovery := shards[:origCount], shards[origCount:]
+	s := decodeShape(orig, recovery)
+	if s.shardSize == 0 {
+		return leopard.Reconstruct(shards, origCount, l.opts...)
+	}
+	c, err := l.get(s)
+	if err != nil {
+		return err
+	}
+	defer l.put(s, c)
+	return c.Reconstruct(shards)
+}
+
+func (l *leopardCodec) MaxShards() int {
+	return leopard.MaxShards
+}
+
+// get returns a leopard.Codec of shape s from its pool, or a new one.
+// Codecs which are dropped from a pool are closed by their finalizer.
+func (l *leopardCodec) get(s shape) (*leopard.Codec, error) {
+	if p, ok := l.pools.Load(s); ok {
+		if c, ok := p.(*sync.Pool).Get().(*leopard.Codec); ok {
+			return c, nil
+		}
+	}
+	c, err := leopard.NewCodec(s.origCount, s.recoveryCount, s.shardSize, l.opts...)
+	if err != nil {
+		return nil, err
+	}
+	runtime.SetFinalizer(c, (*leopard.Codec).Close)
+	return c, nil
+}
+
+// put returns c, which get returned for shape s, to the pool of s.
+func (l *leopardCodec) put(s shape, c *leopard.Codec) {
+	p, ok := l.pools.Load(s)
+	if !ok {
+		p, _ = l.pools.LoadOrStore(s, new(sync.Pool))
+	}
+	p.(*sync.Pool).Put(c)
+}
+
+// decodeShape returns the shape of a codeword of which orig and recovery may
+// lack some shares, taking the share size from both like leopard.Decode.
+func decodeShape(orig, recovery [][]byte) shape {
+	return shape{len(orig), len(recovery), maxInt(shareSize(orig), shareSize(recovery))}
+}
+
+// shareSize returns the size of the first non-empty share, like leopard
+// takes the share size from, or 0 if all shares are empty.
+func shareSize(shares [][]byte) int {
+	for _, s := range shares {
+		if len(s) != 0 {
+			return len(s)
+		}
+	}
+	return 0
+}
+
+func maxInt(x, y int) int {
+	if x < y {
+		return y
+	}
+	return x
+}
+
+func (l *leopardCodec) ValidateShardSize(size int) error {
+	return leopard.ValidateShardSize(size, l.opts...)
+}
@@ -0,0 +1,143 @@
+package leopard
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecReuse(t *testing.T) {
+	const originalCount = 32
+	const recoveryCount = 8
+	const bufferBytes = 128
+
+	c, err := NewCodec(originalCount, recoveryCount, bufferBytes)
+	require.NoError(t, err)
+	defer c.Close()
+
+	for round := 0; round < 10; round++ {
+		originalData := make([][]byte, originalCount)
+		for i := 0; i < originalCount; i++ {
+			originalData[i] = make([]byte, bufferBytes)
+			checkedRandBytes(originalData[i])
+		}
+		origCopy := deepCopy(originalData)
+
+		encoded, err := c.Encode(originalData)
+		require.NoError(t, err)
+		origEnc := deepCopy(encoded)
+
+		// must be identical to the package level function:
+		want, err := EncodeWithRecovery(originalData, recoveryCount)
+		require.NoError(t, err)
+		assert.Equal(t, want, encoded)
+
+		for _, loseIdx := range rand.Perm(originalCount + recoveryCount)[:recoveryCount] {
+			if loseIdx < originalCount {
+				originalData[loseIdx] = nil
+			} else {
+				encoded[loseIdx-originalCount] = nil
+			}
+		}
+		dec, err := c.Decode(originalData, encoded)
+		require.NoError(t, err)
+		assert.Equal(t, origCopy, dec[:originalCount])
+		assert.Equal(t, origEnc, dec[originalCount:])
+	}
+}
+
+func TestCodecConcurrent(t *testing.T) {
+	const originalCount = 64
+	const bufferBytes = 64
+
+	c, err := NewCodec(originalCount, originalCount, bufferBytes)
+	require.NoError(t, err)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			originalData := make([][]byte, originalCount)
+			for i := 0; i < originalCount; i++ {
+				originalData[i] = make([]byte, bufferBytes)
+				checkedRandBytes(originalData[i])
+			}
+			encoded, err := c.Encode(originalData)
+			assert.NoError(t, err)
+			for i := 0; i < originalCount; i++ {
+				originalData[i] = nil
+			}
+			dec, err := c.Recover(originalData, encoded)
+			assert.NoError(t, err)
+			for i := 0; i < originalCount; i++ {
+				assert.True(t, checkBytes(dec[i]))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCodecErrors(t *testing.T) {
+	_, err := NewCodec(4, 5, 64)
+	assert.True(t, errors.Is(err, ErrInvalidCounts), err)
+	assert.Contains(t, err.Error(), "leopard new codec (orig=4, recovery=5)")
+	_, err = NewCodec(4, 4, 65)
+	assert.Equal(t, ErrInvalidSize, err)
+	assert.Equal(t, ErrInvalidSize, ValidateShardSize(65))
+	assert.Equal(t, ErrInvalidSize, ValidateShardSize(0, WithPadding()))
+	// odd sizes can only be padded in GF(2^8):
+	assert.Equal(t, ErrInvalidSize, ValidateShardSize(65, WithPadding()))
+	assert.NoError(t, ValidateShardSize(66, WithPadding()))
+	assert.NoError(t, ValidateShardSize(1<<30))
+
+	c, err := NewCodec(4, 4, 64)
+	require.NoError(t, err)
+
+	_, err = c.Encode(make([][]byte, 3))
+	assert.True(t, errors.Is(err, ErrInvalidCounts))
+
+	data := [][]byte{make([]byte, 64), make([]byte, 64), make([]byte, 128), make([]byte, 64)}
+	_, err = c.Encode(data)
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+
+	require.NoError(t, c.Close())
+	require.NoError(t, c.Close())
+	_, err = c.Encode(data)
+	assert.Equal(t, ErrCodecClosed, err)
+}
+
+func BenchmarkCodecEncode(b *testing.B) {
+	const originalCount = 128
+	const bufferBytes = 512
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	b.Run("Encode", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Encode(originalData); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Codec.Encode", func(b *testing.B) {
+		c, err := NewCodec(originalCount, originalCount, bufferBytes)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer c.Close()
+		for i := 0; i < b.N; i++ {
+			if _, err := c.Encode(originalData); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
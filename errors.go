@@ -0,0 +1,115 @@
+package leopard
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNeedMoreData  = errors.New("not enough recovery data received")
+	ErrTooMuchData   = errors.New("buffer counts are too high")
+	ErrInvalidSize   = errors.New("buffer size must be a multiple of 64 bytes")
+	ErrInvalidCounts = errors.New("invalid counts provided")
+	ErrInvalidInput  = errors.New("a function parameter was invalid")
+	ErrPlatform      = errors.New("platform is unsupported")
+
+	ErrCallInitialize = errors.New("call Init() first")
+
+	ErrCodecClosed = errors.New("codec is closed")
+
+	errAllBuffersEmpty = errors.New("all buffers are empty")
+)
+
+// operations reported in LeopardError:
+const (
+	OpInit   = "init"
+	OpEncode = "encode"
+	OpDecode = "decode"
+	// OpNewCodec reports invalid counts passed to NewCodec directly.
+	OpNewCodec = "new codec"
+)
+
+// LeopardError describes a failed leopard operation.
+// It unwraps to the sentinel error matching Result, e.g.
+// errors.Is(err, ErrNeedMoreData) holds for a failed decode with too many
+// missing shares.
+type LeopardError struct {
+	// Result is the result code leopard returned (or would have returned for
+	// input rejected before calling into leopard).
+	Result Leopardresult
+	// Op is the failed operation, one of OpInit, OpEncode, OpDecode or
+	// OpNewCodec.
+	Op string
+	// OrigCount, RecoveryCount and BufferBytes describe the codeword shape;
+	// they are zero for OpInit, and BufferBytes is zero for invalid counts.
+	OrigCount     int
+	RecoveryCount int
+	BufferBytes   int
+	// Shard is the index of the offending share within (orig || recovery),
+	// or -1 if the error does not relate to a single share.
+	Shard int
+
+	detail string
+}
+
+func (e *LeopardError) Error() string {
+	msg := fmt.Sprintf("leopard %s", e.Op)
+	if e.Op != OpInit {
+		msg += fmt.Sprintf(" (orig=%d, recovery=%d", e.OrigCount, e.RecoveryCount)
+		if e.BufferBytes > 0 {
+			msg += fmt.Sprintf(", bufferBytes=%d", e.BufferBytes)
+		}
+		msg += ")"
+	}
+	if e.Shard >= 0 {
+		msg += fmt.Sprintf(" share %d", e.Shard)
+	}
+	msg += ": " + resultString(e.Result)
+	if e.detail != "" {
+		msg += ": " + e.detail
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel error matching Result or nil for unknown
+// result codes.
+func (e *LeopardError) Unwrap() error {
+	switch e.Result {
+	case LeopardNeedmoredata:
+		return ErrNeedMoreData
+	case LeopardToomuchdata:
+		return ErrTooMuchData
+	case LeopardInvalidsize:
+		return ErrInvalidSize
+	case LeopardInvalidcounts:
+		return ErrInvalidCounts
+	case LeopardInvalidinput:
+		return ErrInvalidInput
+	case LeopardPlatform:
+		return ErrPlatform
+	case LeopardCallinitialize:
+		return ErrCallInitialize
+	default:
+		return nil
+	}
+}
+
+// countsError returns a *LeopardError for a codeword of origCount original
+// and recoveryCount recovery shares rejected before a Codec is created.
+func countsError(op string, errCode Leopardresult, origCount, recoveryCount int, format string, args ...interface{}) *LeopardError {
+	return &LeopardError{
+		Result:        errCode,
+		Op:            op,
+		OrigCount:     origCount,
+		RecoveryCount: recoveryCount,
+		Shard:         -1,
+		detail:        fmt.Sprintf(format, args...),
+	}
+}
+
+func leopardResultToErr(op string, errCode Leopardresult) error {
+	if errCode == LeopardSuccess {
+		return nil
+	}
+	return &LeopardError{Result: errCode, Op: op, Shard: -1}
+}
@@ -0,0 +1,54 @@
+// Package gf exposes the finite field arithmetic leopard uses to encode:
+// GF(2^8) for codewords of up to 256 shares (after rounding the recovery
+// count and then the total to powers of two) and GF(2^16) above that.
+//
+// Elements are represented exactly as leopard represents the symbols of a
+// share, i.e. in leopard's Cantor basis rather than the usual polynomial
+// basis. Addition is xor in either basis, but multiplication results only
+// line up with leopard's recovery shares when using this package.
+//
+// In GF(2^8) every byte of a share is one symbol. In GF(2^16) a share is a
+// sequence of 64 byte blocks, each holding 32 symbols: symbol j of a block
+// consists of the low byte at offset j and the high byte at offset 32+j.
+// Use Symbol16 and SetSymbol16 to access the symbols of a share.
+package gf
+
+import (
+	"sync"
+
+	"github.com/celestiaorg/go-leopard/internal/goleo"
+)
+
+// blockBytes is the size of the blocks GF(2^16) symbols are stored in.
+const blockBytes = 64
+
+func checkLengths(dst, src []byte) {
+	if len(dst) != len(src) {
+		panic("gf: slices of different length")
+	}
+}
+
+func checkBlocks(dst, src []byte) {
+	checkLengths(dst, src)
+	if len(dst)%blockBytes != 0 {
+		panic("gf: GF(2^16) slices must be a multiple of 64 bytes")
+	}
+}
+
+// The tables of both fields are built on first use, so that importing the
+// package costs nothing:
+var (
+	ff8Once, ff16Once sync.Once
+	ff8Field          goleo.Field
+	ff16Field         goleo.Field
+)
+
+func ff8() goleo.Field {
+	ff8Once.Do(func() { ff8Field = goleo.FF8() })
+	return ff8Field
+}
+
+func ff16() goleo.Field {
+	ff16Once.Do(func() { ff16Field = goleo.FF16() })
+	return ff16Field
+}
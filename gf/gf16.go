@@ -0,0 +1,89 @@
+package gf
+
+// GF16 is an element of leopard's GF(2^16).
+type GF16 uint16
+
+// Order16 is the number of elements of GF(2^16).
+const Order16 = 1 << 16
+
+// Add returns a+b (which equals a-b).
+func (a GF16) Add(b GF16) GF16 { return a ^ b }
+
+// Mul returns a*b.
+func (a GF16) Mul(b GF16) GF16 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	f := ff16()
+	return GF16(f.MulLog(uint16(a), f.Log(uint16(b))))
+}
+
+// Inverse returns the multiplicative inverse of a. It panics if a is zero.
+func (a GF16) Inverse() GF16 {
+	if a == 0 {
+		panic("gf: inverse of zero")
+	}
+	f := ff16()
+	return GF16(f.Exp(f.Modulus() - f.Log(uint16(a))))
+}
+
+// Div returns a/b. It panics if b is zero.
+func (a GF16) Div(b GF16) GF16 { return a.Mul(b.Inverse()) }
+
+// Log returns the discrete logarithm of a, in [0, Order16-1).
+// It panics if a is zero.
+func (a GF16) Log() int {
+	if a == 0 {
+		panic("gf: logarithm of zero")
+	}
+	return int(ff16().Log(uint16(a)))
+}
+
+// Exp16 returns the element with discrete logarithm n, i.e. the inverse of
+// GF16.Log.
+func Exp16(n int) GF16 {
+	n %= Order16 - 1
+	if n < 0 {
+		n += Order16 - 1
+	}
+	return GF16(ff16().Exp(uint16(n)))
+}
+
+// Symbol16 returns the i-th GF(2^16) symbol of the share buf.
+func Symbol16(buf []byte, i int) GF16 {
+	off := i/32*blockBytes + i%32
+	return GF16(buf[off]) | GF16(buf[off+32])<<8
+}
+
+// SetSymbol16 sets the i-th GF(2^16) symbol of the share buf to v.
+func SetSymbol16(buf []byte, i int, v GF16) {
+	off := i/32*blockBytes + i%32
+	buf[off] = byte(v)
+	buf[off+32] = byte(v >> 8)
+}
+
+// MulSlice16 sets dst = c*src symbol by symbol, see Symbol16 for the layout.
+// dst and src must have the same length, which must be a multiple of 64.
+func MulSlice16(dst, src []byte, c GF16) {
+	checkBlocks(dst, src)
+	if c == 0 {
+		for i := range dst {
+			dst[i] = 0
+		}
+		return
+	}
+	f := ff16()
+	f.MulBytes(dst, src, f.Log(uint16(c)))
+}
+
+// MulAddSlice16 sets dst ^= c*src symbol by symbol, see Symbol16 for the
+// layout. dst and src must have the same length, which must be a multiple of
+// 64.
+func MulAddSlice16(dst, src []byte, c GF16) {
+	checkBlocks(dst, src)
+	if c == 0 {
+		return
+	}
+	f := ff16()
+	f.MulAddBytes(dst, src, f.Log(uint16(c)))
+}
@@ -0,0 +1,75 @@
+package gf
+
+// GF8 is an element of leopard's GF(2^8).
+type GF8 uint8
+
+// Order8 is the number of elements of GF(2^8).
+const Order8 = 1 << 8
+
+// Add returns a+b (which equals a-b).
+func (a GF8) Add(b GF8) GF8 { return a ^ b }
+
+// Mul returns a*b.
+func (a GF8) Mul(b GF8) GF8 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	f := ff8()
+	return GF8(f.MulLog(uint16(a), f.Log(uint16(b))))
+}
+
+// Inverse returns the multiplicative inverse of a. It panics if a is zero.
+func (a GF8) Inverse() GF8 {
+	if a == 0 {
+		panic("gf: inverse of zero")
+	}
+	f := ff8()
+	return GF8(f.Exp(f.Modulus() - f.Log(uint16(a))))
+}
+
+// Div returns a/b. It panics if b is zero.
+func (a GF8) Div(b GF8) GF8 { return a.Mul(b.Inverse()) }
+
+// Log returns the discrete logarithm of a, in [0, Order8-1).
+// It panics if a is zero.
+func (a GF8) Log() int {
+	if a == 0 {
+		panic("gf: logarithm of zero")
+	}
+	return int(ff8().Log(uint16(a)))
+}
+
+// Exp8 returns the element with discrete logarithm n, i.e. the inverse of
+// GF8.Log.
+func Exp8(n int) GF8 {
+	n %= Order8 - 1
+	if n < 0 {
+		n += Order8 - 1
+	}
+	return GF8(ff8().Exp(uint16(n)))
+}
+
+// MulSlice8 sets dst = c*src, treating every byte as a symbol.
+// dst and src must have the same length.
+func MulSlice8(dst, src []byte, c GF8) {
+	checkLengths(dst, src)
+	if c == 0 {
+		for i := range dst {
+			dst[i] = 0
+		}
+		return
+	}
+	f := ff8()
+	f.MulBytes(dst, src, f.Log(uint16(c)))
+}
+
+// MulAddSlice8 sets dst ^= c*src, treating every byte as a symbol.
+// dst and src must have the same length.
+func MulAddSlice8(dst, src []byte, c GF8) {
+	checkLengths(dst, src)
+	if c == 0 {
+		return
+	}
+	f := ff8()
+	f.MulAddBytes(dst, src, f.Log(uint16(c)))
+}
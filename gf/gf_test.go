@@ -0,0 +1,99 @@
+package gf_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	leopard "github.com/celestiaorg/go-leopard"
+	"github.com/celestiaorg/go-leopard/gf"
+)
+
+func TestGF8(t *testing.T) {
+	for a := 1; a < gf.Order8; a++ {
+		x := gf.GF8(a)
+		require.Equal(t, gf.GF8(1), x.Mul(x.Inverse()))
+		require.Equal(t, x, gf.Exp8(x.Log()))
+		require.Equal(t, gf.GF8(0), x.Mul(0))
+	}
+	a, b, c := gf.GF8(3), gf.GF8(0x57), gf.GF8(0xc4)
+	assert.Equal(t, a.Mul(b.Add(c)), a.Mul(b).Add(a.Mul(c)))
+	assert.Equal(t, a, a.Mul(b).Div(b))
+}
+
+func TestGF16(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		x := gf.GF16(rand.Intn(gf.Order16-1) + 1)
+		require.Equal(t, gf.GF16(1), x.Mul(x.Inverse()))
+		require.Equal(t, x, gf.Exp16(x.Log()))
+	}
+	a, b, c := gf.GF16(3), gf.GF16(0x1257), gf.GF16(0xc4f0)
+	assert.Equal(t, a.Mul(b.Add(c)), a.Mul(b).Add(a.Mul(c)))
+	assert.Equal(t, a, a.Mul(b).Div(b))
+}
+
+func TestSlices(t *testing.T) {
+	src := make([]byte, 128)
+	rand.Read(src)
+	dst := make([]byte, 128)
+
+	gf.MulSlice8(dst, src, 0x35)
+	gf.MulAddSlice8(dst, src, 0x35)
+	assert.Equal(t, make([]byte, 128), dst)
+
+	gf.MulSlice16(dst, src, 0x1235)
+	for i := 0; i < 64; i++ {
+		assert.Equal(t, gf.Symbol16(src, i).Mul(0x1235), gf.Symbol16(dst, i))
+	}
+	assert.Panics(t, func() { gf.MulSlice16(dst[:100], src[:100], 1) })
+}
+
+// TestConsistentWithEncode checks that the recovery shares are the linear
+// combinations of the original shares computed with this package, in both
+// fields.
+func TestConsistentWithEncode(t *testing.T) {
+	const shardSize = 128
+	for _, counts := range [][2]int{{16, 8}, {300, 100}} {
+		origCount, recoveryCount := counts[0], counts[1]
+		wide := origCount > 128
+
+		// encoding unit vectors yields the coefficients of the code:
+		coeffs := make([][][]byte, origCount)
+		for i := range coeffs {
+			unit := make([][]byte, origCount)
+			for j := range unit {
+				unit[j] = make([]byte, shardSize)
+			}
+			if wide {
+				gf.SetSymbol16(unit[i], 0, 1)
+			} else {
+				unit[i][0] = 1
+			}
+			var err error
+			coeffs[i], err = leopard.EncodeWithRecovery(unit, recoveryCount)
+			require.NoError(t, err)
+		}
+
+		data := make([][]byte, origCount)
+		for i := range data {
+			data[i] = make([]byte, shardSize)
+			rand.Read(data[i])
+		}
+		parity, err := leopard.EncodeWithRecovery(data, recoveryCount)
+		require.NoError(t, err)
+
+		for r := 0; r < recoveryCount; r++ {
+			want := make([]byte, shardSize)
+			for i := range data {
+				if wide {
+					gf.MulAddSlice16(want, data[i], gf.Symbol16(coeffs[i][r], 0))
+				} else {
+					gf.MulAddSlice8(want, data[i], gf.GF8(coeffs[i][r][0]))
+				}
+			}
+			require.Equal(t, want, parity[r], "recovery share %d", r)
+		}
+	}
+}
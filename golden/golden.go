@@ -0,0 +1,149 @@
+// Package golden provides deterministic test vectors pinning the recovery
+// shares leopard computes, so that any change to the encoding (e.g. by
+// updating the C library) is detected.
+//
+// Each Vector describes a codeword shape and a seed; the original shares are
+// derived from the seed (see Vector.Data) and the expected recovery shares
+// are stored along. The vectors returned by Vectors cover codewords encoded
+// in GF(2^8) as well as GF(2^16) and are checked by this package's tests.
+// Use Vectors and Check to verify other encoders against them.
+//
+// The vectors are generated into vectors.go by make golden, which computes
+// them with the C library (refusing to use the pure Go port) and records the
+// leopard commit used. Until that file has been generated, Vectors returns
+// none and the checks against them are skipped. Once generated, the vectors
+// must only be regenerated if a change of the encoding is intended, which
+// breaks compatibility with data encoded before.
+package golden
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Vector is a single test vector.
+type Vector struct {
+	Name          string `json:"name"`
+	OrigCount     int    `json:"origCount"`
+	RecoveryCount int    `json:"recoveryCount"`
+	ShardSize     int    `json:"shardSize"`
+	Seed          uint64 `json:"seed"`
+	// Parity holds the expected recovery shares.
+	Parity [][]byte `json:"parity"`
+}
+
+// EncodeFunc computes recoveryCount recovery shares for data, e.g.
+// leopard.EncodeWithRecovery.
+type EncodeFunc func(data [][]byte, recoveryCount int) ([][]byte, error)
+
+// file is the format read by Load and written by Write.
+type file struct {
+	Vectors []Vector `json:"vectors"`
+}
+
+// Data returns the original shares of the vector.
+// Share i consists of the concatenated SHA-256 hashes of
+// ("leopard golden", seed, i, j) for j = 0, 1, ..., encoded as big endian
+// uint64s, truncated to ShardSize bytes.
+func (v Vector) Data() [][]byte {
+	data := make([][]byte, v.OrigCount)
+	for i := range data {
+		data[i] = make([]byte, 0, v.ShardSize+sha256.Size)
+		var in [14 + 3*8]byte
+		copy(in[:], "leopard golden")
+		binary.BigEndian.PutUint64(in[14:], v.Seed)
+		binary.BigEndian.PutUint64(in[22:], uint64(i))
+		for j := uint64(0); len(data[i]) < v.ShardSize; j++ {
+			binary.BigEndian.PutUint64(in[30:], j)
+			sum := sha256.Sum256(in[:])
+			data[i] = append(data[i], sum[:]...)
+		}
+		data[i] = data[i][:v.ShardSize]
+	}
+	return data
+}
+
+// Check encodes the vector's data with encode and compares the result to
+// the expected recovery shares.
+func Check(v Vector, encode EncodeFunc) error {
+	parity, err := encode(v.Data(), v.RecoveryCount)
+	if err != nil {
+		return fmt.Errorf("golden: %s: %w", v.Name, err)
+	}
+	if len(parity) != len(v.Parity) {
+		return fmt.Errorf("golden: %s: got %d recovery shares, expected %d", v.Name, len(parity), len(v.Parity))
+	}
+	for i := range parity {
+		if !bytes.Equal(parity[i], v.Parity[i]) {
+			return fmt.Errorf("golden: %s: recovery share %d differs", v.Name, i)
+		}
+	}
+	return nil
+}
+
+// Generate returns a vector for the given shape and seed with the recovery
+// shares computed by encode.
+func Generate(origCount, recoveryCount, shardSize int, seed uint64, encode EncodeFunc) (Vector, error) {
+	v := Vector{
+		Name:          fmt.Sprintf("%d+%d/%d", origCount, recoveryCount, shardSize),
+		OrigCount:     origCount,
+		RecoveryCount: recoveryCount,
+		ShardSize:     shardSize,
+		Seed:          seed,
+	}
+	parity, err := encode(v.Data(), recoveryCount)
+	if err != nil {
+		return Vector{}, fmt.Errorf("golden: %s: %w", v.Name, err)
+	}
+	v.Parity = parity
+	return v, nil
+}
+
+// vectorsJSON holds the vectors returned by Vectors in the format written by
+// Write. It is set by the generated vectors.go, see make golden.
+var vectorsJSON string
+
+// Vectors returns this package's test vectors, or nil if they haven't been
+// generated (with make golden) yet.
+func Vectors() []Vector {
+	if vectorsJSON == "" {
+		return nil
+	}
+	vectors, err := Load(strings.NewReader(vectorsJSON))
+	if err != nil {
+		panic(err)
+	}
+	return vectors
+}
+
+// Load reads vectors in the format written by Write from r.
+func Load(r io.Reader) ([]Vector, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("golden: %w", err)
+	}
+	return f.Vectors, nil
+}
+
+// LoadFile reads the vectors stored at path in the format written by Write.
+func LoadFile(path string) ([]Vector, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Write writes vectors in the format read by Load to w.
+func Write(w io.Writer, vectors []Vector) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(file{Vectors: vectors})
+}
@@ -0,0 +1,129 @@
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	leopard "github.com/celestiaorg/go-leopard"
+)
+
+var (
+	update        = flag.Bool("update", false, "regenerate vectors.go with the C library")
+	leopardCommit = flag.String("leopard-commit", "", "commit of the leopard C library used by -update, recorded in vectors.go")
+)
+
+const vectorsFile = "vectors.go"
+
+// shapes are the (origCount, recoveryCount, shardSize) triples of Vectors,
+// codewords of up to 256 shares after rounding are encoded in
+// GF(2^8), larger ones in GF(2^16).
+var shapes = [][3]int{
+	// GF(2^8), including the special cases of a single original or
+	// recovery share:
+	{1, 1, 64},
+	{2, 1, 64},
+	{7, 1, 128},
+	{2, 2, 64},
+	{3, 2, 128},
+	{4, 4, 64},
+	{10, 3, 192},
+	{16, 16, 64},
+	{32, 8, 640},
+	{64, 64, 64},
+	{100, 28, 128},
+	{128, 128, 64},
+	// GF(2^16):
+	{129, 127, 64},
+	{200, 100, 64},
+	{256, 256, 64},
+	{1000, 24, 64},
+	{300, 300, 128},
+	{3000, 1, 64}, // a single recovery share is the XOR of all shares
+	{3000, 1000, 64},
+}
+
+func encode(data [][]byte, recoveryCount int) ([][]byte, error) {
+	return leopard.EncodeWithRecovery(data, recoveryCount)
+}
+
+func encodePureGo(data [][]byte, recoveryCount int) ([][]byte, error) {
+	return leopard.EncodeWithRecovery(data, recoveryCount, leopard.WithPureGo())
+}
+
+func TestVectors(t *testing.T) {
+	vectors := Vectors()
+	if *update {
+		// the vectors pin the C library's output; generating them with the
+		// pure Go port would pin its bugs instead:
+		caps := leopard.Capabilities()
+		require.False(t, caps.PureGo, "vectors must be generated with the C library, build with cgo")
+		require.NoError(t, caps.InitErr)
+		require.NotEmpty(t, *leopardCommit, "-leopard-commit is required with -update, see make golden")
+		vectors = make([]Vector, len(shapes))
+		for i, s := range shapes {
+			var err error
+			vectors[i], err = Generate(s[0], s[1], s[2], uint64(i), encode)
+			require.NoError(t, err)
+		}
+		require.NoError(t, writeVectorsFile(vectors, *leopardCommit))
+	}
+	if vectors == nil {
+		t.Skip("no golden vectors, generate them with the C library by running make golden")
+	}
+
+	require.Len(t, vectors, len(shapes))
+	for _, v := range vectors {
+		assert.NoError(t, Check(v, encode))
+		assert.NoError(t, Check(v, encodePureGo))
+	}
+}
+
+// writeVectorsFile writes vectors to vectorsFile as the vectorsJSON
+// constant, recording that they were computed by the C library at commit.
+func writeVectorsFile(vectors []Vector, commit string) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, vectors); err != nil {
+		return err
+	}
+	src := fmt.Sprintf(`// Code generated by go test ./golden -update. DO NOT EDIT.
+
+// The recovery shares were computed by the leopard C library (leopard.h
+// version %d) built from commit %s of the leopard submodule,
+// by running: make golden
+
+package golden
+
+func init() {
+	vectorsJSON = %s
+}
+`, leopard.Capabilities().HeaderVersion, commit, "`"+buf.String()+"`")
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(vectorsFile, formatted, 0o644)
+}
+
+func TestCheckDetectsChanges(t *testing.T) {
+	v, err := Generate(2, 2, 64, 3, encode)
+	require.NoError(t, err)
+	require.NoError(t, Check(v, encodePureGo))
+	v.Parity[1][5] ^= 1
+	assert.EqualError(t, Check(v, encode), "golden: 2+2/64: recovery share 1 differs")
+}
+
+func TestData(t *testing.T) {
+	v := Vector{OrigCount: 2, ShardSize: 100, Seed: 1}
+	data := v.Data()
+	require.Len(t, data, 2)
+	assert.Len(t, data[0], 100)
+	assert.NotEqual(t, data[0], data[1])
+	assert.Equal(t, data, v.Data())
+}
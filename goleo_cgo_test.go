@@ -0,0 +1,119 @@
+//go:build cgo
+// +build cgo
+
+package leopard
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/celestiaorg/go-leopard/internal/goleo"
+	cleo "github.com/celestiaorg/go-leopard/leopard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGoleoMatchesC runs the pure Go port and the C library side by side on
+// random codeword shapes in both fields and requires byte identical recovery
+// shares and decoder output, calling both APIs directly rather than through
+// a Codec.
+func TestGoleoMatchesC(t *testing.T) {
+	require.NoError(t, Init())
+	seed := time.Now().UnixNano()
+	t.Logf("seed %d", seed)
+	rnd := rand.New(rand.NewSource(seed))
+
+	// randomShape returns a shape whose field is GF(2^8) if maxTotal is 256
+	// and mostly GF(2^16) otherwise:
+	randomShape := func(maxTotal int) (int, int) {
+		for {
+			k := 1 + rnd.Intn(maxTotal-1)
+			m := 1 + rnd.Intn(k)
+			if checkCounts(OpEncode, k, m) == nil && (k == 1 || m == 1 || k+nextPow2(m) <= maxTotal) {
+				return k, m
+			}
+		}
+	}
+	shapes := [][2]int{{1, 1}, {2, 1}, {7, 1}, {2, 2}, {128, 128}, {129, 127}, {256, 1}, {1000, 24}}
+	for i := 0; i < 20; i++ {
+		k, m := randomShape(256)
+		shapes = append(shapes, [2]int{k, m})
+	}
+	for i := 0; i < 10; i++ {
+		k, m := randomShape(4096)
+		shapes = append(shapes, [2]int{k, m})
+	}
+	for _, shape := range shapes {
+		k, m := shape[0], shape[1]
+		bufferBytes := 64 * (1 + rnd.Intn(4))
+		t.Run(fmt.Sprintf("%d+%d/%d", k, m, bufferBytes), func(t *testing.T) {
+			data := make([][]byte, k)
+			for i := range data {
+				data[i] = make([]byte, bufferBytes)
+				rnd.Read(data[i])
+			}
+
+			// encode:
+			cOrig := mallocBuffers(k, bufferBytes)
+			defer freeAll(cOrig)
+			for i, d := range data {
+				copy(cBytes(cOrig[i], bufferBytes), d)
+			}
+			encodeWorkCount := cleo.LeoEncodeWorkCount(uint32(k), uint32(m))
+			require.Equal(t, int(encodeWorkCount), goleo.EncodeWorkCount(k, m))
+			cWork := mallocBuffers(int(encodeWorkCount), bufferBytes)
+			defer freeAll(cWork)
+			require.Equal(t, LeopardSuccess,
+				cleo.LeoEncode(uint64(bufferBytes), uint32(k), uint32(m), encodeWorkCount, cOrig, cWork))
+			goWork := allocShares(int(encodeWorkCount), bufferBytes)
+			require.Equal(t, goleo.Success, goleo.Encode(bufferBytes, k, m, data, goWork))
+			recovery := make([][]byte, m)
+			for i := range recovery {
+				recovery[i] = cBytes(cWork[i], bufferBytes)
+				require.Equal(t, recovery[i], goWork[i], "recovery share %d", i)
+			}
+
+			// decode after losing m random shares:
+			lost := NewAvailability(k + m)
+			for _, i := range rnd.Perm(k + m)[:m] {
+				lost.Set(i)
+			}
+			cRecovery := mallocBuffers(m, bufferBytes)
+			defer freeAll(cRecovery)
+			cOrigIn := make([]unsafe.Pointer, k)
+			cRecoveryIn := make([]unsafe.Pointer, m)
+			goOrigIn := make([][]byte, k)
+			goRecoveryIn := make([][]byte, m)
+			for i := 0; i < k; i++ {
+				if !lost.Has(i) {
+					cOrigIn[i], goOrigIn[i] = cOrig[i], data[i]
+				}
+			}
+			for i := 0; i < m; i++ {
+				copy(cBytes(cRecovery[i], bufferBytes), recovery[i])
+				if !lost.Has(k + i) {
+					cRecoveryIn[i], goRecoveryIn[i] = cRecovery[i], goWork[i]
+				}
+			}
+			decodeWorkCount := cleo.LeoDecodeWorkCount(uint32(k), uint32(m))
+			require.Equal(t, int(decodeWorkCount), goleo.DecodeWorkCount(k, m))
+			cDecodeWork := mallocBuffers(int(decodeWorkCount), bufferBytes)
+			defer freeAll(cDecodeWork)
+			cRes := cleo.LeoDecode(uint64(bufferBytes), uint32(k), uint32(m), decodeWorkCount,
+				cOrigIn, cRecoveryIn, cDecodeWork)
+			goDecodeWork := allocShares(int(decodeWorkCount), bufferBytes)
+			goRes := goleo.Decode(bufferBytes, k, m, goOrigIn, goRecoveryIn, goDecodeWork)
+			require.Equal(t, cRes, Leopardresult(goRes))
+			require.Equal(t, LeopardSuccess, cRes)
+			for i := 0; i < k; i++ {
+				if lost.Has(i) {
+					assert.Equal(t, cBytes(cDecodeWork[i], bufferBytes), goDecodeWork[i], "decoded share %d", i)
+					assert.Equal(t, data[i], goDecodeWork[i], "decoded share %d", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package leopard
+
+import "fmt"
+
+// Interleaver encodes and decodes shard sets too large for a single leopard
+// codeword (more than MaxShards original plus recovery shares) by spreading
+// them across several independent codewords.
+// Shares are interleaved: original share i belongs to codeword i%Codewords()
+// as do recovery share i, so a burst of lost consecutive shares hits every
+// codeword evenly.
+// Like a Codec, an Interleaver is safe for concurrent use and must be closed.
+type Interleaver struct {
+	origCount     int
+	recoveryCount int
+	shardSize     int
+	codewords     int
+	// codecs by codeword shape, there are at most four different shapes:
+	codecs map[[2]int]*Codec
+}
+
+// CodewordStatus describes the shares of one codeword of an Interleaver.
+type CodewordStatus struct {
+	OrigCount     int
+	RecoveryCount int
+	// Present is the number of shares of the codeword that are present,
+	// the codeword can be recovered if at least OrigCount are.
+	Present     int
+	Recoverable bool
+}
+
+// UnrecoverableError is returned by Interleaver.Reconstruct if some of the
+// codewords miss too many shares to be recovered. The other codewords are
+// recovered nevertheless.
+// It unwraps to ErrNeedMoreData.
+type UnrecoverableError struct {
+	// Codewords are the indices of the unrecoverable codewords.
+	Codewords []int
+	// Total is the number of codewords.
+	Total int
+}
+
+func (e *UnrecoverableError) Error() string {
+	return fmt.Sprintf("%d of %d codewords can't be recovered: %v", len(e.Codewords), e.Total, e.Codewords)
+}
+
+func (e *UnrecoverableError) Unwrap() error {
+	return ErrNeedMoreData
+}
+
+// NewInterleaver returns an Interleaver for origCount original and
+// recoveryCount recovery shares of shardSize bytes each. It uses as few
+// codewords as possible (see WithCodewordSize to use smaller ones), which
+// requires recoveryCount to be in [Codewords(), origCount].
+func NewInterleaver(origCount, recoveryCount, shardSize int, opts ...Option) (*Interleaver, error) {
+	o := newOptions(opts)
+	if recoveryCount < 1 || recoveryCount > origCount {
+		return nil, ErrInvalidCounts
+	}
+	maxShares := MaxShards
+	if o.codewordSize > 0 && o.codewordSize < maxShares {
+		maxShares = o.codewordSize
+	}
+	codewords := (origCount + recoveryCount + maxShares - 1) / maxShares
+	for ; codewords <= recoveryCount; codewords++ {
+		// the first codeword is the largest one:
+		k, m := ceilDiv(origCount, codewords), ceilDiv(recoveryCount, codewords)
+		if codewordShares(k, m) <= maxShares && checkCounts(OpNewCodec, k, m) == nil {
+			break
+		}
+	}
+	if codewords > recoveryCount {
+		return nil, fmt.Errorf("%w: %d recovery shares are too few to spread %d+%d shares over codewords of %d shares",
+			ErrInvalidCounts, recoveryCount, origCount, recoveryCount, maxShares)
+	}
+
+	il := &Interleaver{
+		origCount:     origCount,
+		recoveryCount: recoveryCount,
+		shardSize:     shardSize,
+		codewords:     codewords,
+		codecs:        make(map[[2]int]*Codec),
+	}
+	for j := 0; j < codewords; j++ {
+		shape := il.shape(j)
+		if il.codecs[shape] != nil {
+			continue
+		}
+		c, err := NewCodec(shape[0], shape[1], shardSize, opts...)
+		if err != nil {
+			il.Close()
+			return nil, err
+		}
+		il.codecs[shape] = c
+	}
+	return il, nil
+}
+
+// Codewords returns the number of codewords the shares are spread across.
+func (il *Interleaver) Codewords() int { return il.codewords }
+
+// OrigCount returns the total number of original shares.
+func (il *Interleaver) OrigCount() int { return il.origCount }
+
+// RecoveryCount returns the total number of recovery shares.
+func (il *Interleaver) RecoveryCount() int { return il.recoveryCount }
+
+// ShardSize returns the size of a single share in bytes.
+func (il *Interleaver) ShardSize() int { return il.shardSize }
+
+// Close releases the memory held by the Codecs of the Interleaver.
+func (il *Interleaver) Close() error {
+	for _, c := range il.codecs {
+		c.Close()
+	}
+	return nil
+}
+
+// Encode computes the RecoveryCount recovery shares for data, which must
+// consist of OrigCount shares of ShardSize bytes.
+func (il *Interleaver) Encode(data [][]byte) ([][]byte, error) {
+	parity := allocShares(il.recoveryCount, il.shardSize)
+	if err := il.EncodeInto(data, parity); err != nil {
+		return nil, err
+	}
+	return parity, nil
+}
+
+// EncodeInto computes the recovery shares for data like Encode, but writes
+// them into parity, which must consist of RecoveryCount shares of ShardSize
+// bytes.
+func (il *Interleaver) EncodeInto(data, parity [][]byte) error {
+	if len(data) != il.origCount || len(parity) != il.recoveryCount {
+		return fmt.Errorf("%w: expected %d+%d shares, got %d+%d",
+			ErrInvalidCounts, il.origCount, il.recoveryCount, len(data), len(parity))
+	}
+	for j := 0; j < il.codewords; j++ {
+		shape := il.shape(j)
+		if err := il.codecs[shape].EncodeInto(il.gather(data, j), il.gather(parity, j)); err != nil {
+			return fmt.Errorf("codeword %d: %w", j, err)
+		}
+	}
+	return nil
+}
+
+// Status reports for every codeword how many of its shares are present in
+// shards, which is (orig || recovery) with missing shares being empty.
+func (il *Interleaver) Status(shards [][]byte) ([]CodewordStatus, error) {
+	if err := il.checkShards(shards); err != nil {
+		return nil, err
+	}
+	status := make([]CodewordStatus, il.codewords)
+	for j := range status {
+		shape := il.shape(j)
+		present := len(il.codeword(shards, j)) - countMissing(il.codeword(shards, j))
+		status[j] = CodewordStatus{
+			OrigCount:     shape[0],
+			RecoveryCount: shape[1],
+			Present:       present,
+			Recoverable:   present >= shape[0],
+		}
+	}
+	return status, nil
+}
+
+// Reconstruct takes (orig || recovery) with missing shares set to nil and
+// recovers the missing shares in place, like Codec.Reconstruct.
+// Codewords missing too many shares are left as is and reported in an
+// *UnrecoverableError; all other codewords are recovered.
+func (il *Interleaver) Reconstruct(shards [][]byte) error {
+	if err := il.checkShards(shards); err != nil {
+		return err
+	}
+	var unrecoverable []int
+	for j := 0; j < il.codewords; j++ {
+		shape := il.shape(j)
+		cw := il.codeword(shards, j)
+		if countMissing(cw) == 0 {
+			continue
+		}
+		if len(cw)-countMissing(cw) < shape[0] {
+			unrecoverable = append(unrecoverable, j)
+			continue
+		}
+		if err := il.codecs[shape].Reconstruct(cw); err != nil {
+			return fmt.Errorf("codeword %d: %w", j, err)
+		}
+		il.scatter(shards, cw, j)
+	}
+	if len(unrecoverable) > 0 {
+		return &UnrecoverableError{Codewords: unrecoverable, Total: il.codewords}
+	}
+	return nil
+}
+
+func (il *Interleaver) checkShards(shards [][]byte) error {
+	total := il.origCount + il.recoveryCount
+	if len(shards) != total {
+		return fmt.Errorf("%w: expected %d shares, got %d", ErrInvalidCounts, total, len(shards))
+	}
+	for i, s := range shards {
+		if len(s) != 0 && len(s) != il.shardSize {
+			return fmt.Errorf("%w: share %d has %d bytes, expected %d", ErrInvalidInput, i, len(s), il.shardSize)
+		}
+	}
+	return nil
+}
+
+// shape returns the original and recovery count of codeword j.
+func (il *Interleaver) shape(j int) [2]int {
+	return [2]int{ceilDiv(il.origCount-j, il.codewords), ceilDiv(il.recoveryCount-j, il.codewords)}
+}
+
+// gather returns the shares of codeword j within shares, which are either
+// all original or all recovery shares. It does not copy.
+func (il *Interleaver) gather(shares [][]byte, j int) [][]byte {
+	views := make([][]byte, 0, ceilDiv(len(shares)-j, il.codewords))
+	for i := j; i < len(shares); i += il.codewords {
+		views = append(views, shares[i])
+	}
+	return views
+}
+
+// codeword returns (orig || recovery) of codeword j within shards.
+func (il *Interleaver) codeword(shards [][]byte, j int) [][]byte {
+	return append(il.gather(shards[:il.origCount], j), il.gather(shards[il.origCount:], j)...)
+}
+
+// scatter writes the shares of codeword j back to shards.
+func (il *Interleaver) scatter(shards, cw [][]byte, j int) {
+	k := il.shape(j)[0]
+	for p, s := range cw {
+		if p < k {
+			shards[j+p*il.codewords] = s
+		} else {
+			shards[il.origCount+j+(p-k)*il.codewords] = s
+		}
+	}
+}
+
+// EncodeInterleaved computes recoveryCount recovery shares for data,
+// spreading the shares across as many codewords as needed, see Interleaver.
+func EncodeInterleaved(data [][]byte, recoveryCount int, opts ...Option) ([][]byte, error) {
+	if len(data) == 0 || len(data[0]) == 0 {
+		return nil, errAllBuffersEmpty
+	}
+	il, err := NewInterleaver(len(data), recoveryCount, len(data[0]), opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer il.Close()
+	return il.Encode(data)
+}
+
+// ReconstructInterleaved recovers the missing shares of (orig || recovery)
+// encoded with EncodeInterleaved in place, see Interleaver.Reconstruct.
+func ReconstructInterleaved(shards [][]byte, origCount int, opts ...Option) error {
+	if origCount < 0 || origCount > len(shards) {
+		return ErrInvalidCounts
+	}
+	shardSize := 0
+	for _, s := range shards {
+		if len(s) != 0 {
+			shardSize = len(s)
+			break
+		}
+	}
+	if shardSize == 0 {
+		return errAllBuffersEmpty
+	}
+	il, err := NewInterleaver(origCount, len(shards)-origCount, shardSize, opts...)
+	if err != nil {
+		return err
+	}
+	defer il.Close()
+	return il.Reconstruct(shards)
+}
+
+// codewordShares returns the number of shares leopard works on internally
+// for a codeword, which rounds the recovery count up to a power of two unless
+// one of the counts is 1.
+func codewordShares(origCount, recoveryCount int) int {
+	if origCount == 1 || recoveryCount == 1 {
+		return origCount + recoveryCount
+	}
+	return origCount + nextPow2(recoveryCount)
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,122 @@
+package leopard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterleaver(t *testing.T) {
+	const originalCount = 1000
+	const recoveryCount = 500
+	const bufferBytes = 64
+
+	il, err := NewInterleaver(originalCount, recoveryCount, bufferBytes, WithCodewordSize(256))
+	require.NoError(t, err)
+	defer il.Close()
+	// 1500 shares in codewords of at most 256 (with the rounding of the
+	// recovery count) take 8 codewords of 125+63 shares (or one less):
+	assert.Equal(t, 8, il.Codewords())
+
+	originalData := make([][]byte, originalCount)
+	for i := range originalData {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	parity, err := il.Encode(originalData)
+	require.NoError(t, err)
+	want := append(deepCopy(originalData), deepCopy(parity)...)
+
+	// every codeword is a regular leopard codeword:
+	cw := il.gather(originalData, 3)
+	cwParity, err := EncodeWithRecovery(cw, len(il.gather(parity, 3)))
+	require.NoError(t, err)
+	assert.Equal(t, il.gather(parity, 3), cwParity)
+
+	// a burst of lost shares is spread across all codewords, each of which
+	// can lose 62 shares:
+	shards := append(deepCopy(originalData), deepCopy(parity)...)
+	for i := 100; i < 100+62*il.Codewords(); i++ {
+		shards[i] = nil
+	}
+	status, err := il.Status(shards)
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.True(t, s.Recoverable)
+	}
+	require.NoError(t, il.Reconstruct(shards))
+	assert.Equal(t, want, shards)
+
+	// losing too many shares of a single codeword only fails that one:
+	for i := 5; i < originalCount; i += il.Codewords() {
+		shards[i] = nil
+	}
+	shards[0] = nil
+	status, err = il.Status(shards)
+	require.NoError(t, err)
+	assert.False(t, status[5].Recoverable)
+	err = il.Reconstruct(shards)
+	var unrecoverable *UnrecoverableError
+	require.True(t, errors.As(err, &unrecoverable))
+	assert.True(t, errors.Is(err, ErrNeedMoreData))
+	assert.Equal(t, []int{5}, unrecoverable.Codewords)
+	assert.Equal(t, want[0], shards[0])
+}
+
+func TestInterleaverBeyondMaxShards(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large codewords in short mode")
+	}
+	const originalCount = 50000
+	const recoveryCount = 30000
+	const bufferBytes = 64
+
+	originalData := make([][]byte, originalCount)
+	for i := range originalData {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	_, err := EncodeWithRecovery(originalData, recoveryCount)
+	assert.True(t, errors.Is(err, ErrTooMuchData), err)
+
+	il, err := NewInterleaver(originalCount, recoveryCount, bufferBytes)
+	require.NoError(t, err)
+	defer il.Close()
+	// 80000 shares take 2 codewords of 25000+15000 shares:
+	require.Equal(t, 2, il.Codewords())
+
+	parity, err := EncodeInterleaved(originalData, recoveryCount)
+	require.NoError(t, err)
+	require.Len(t, parity, recoveryCount)
+	want := append(deepCopy(originalData), deepCopy(parity)...)
+
+	// every codeword is a regular leopard codeword:
+	cwParity, err := EncodeWithRecovery(il.gather(originalData, 1), recoveryCount/2)
+	require.NoError(t, err)
+	assert.Equal(t, il.gather(parity, 1), cwParity)
+
+	// a burst of lost original and recovery shares, 15000 per codeword:
+	shards := append(deepCopy(originalData), deepCopy(parity)...)
+	for i := originalCount - recoveryCount/2; i < originalCount+recoveryCount/2; i++ {
+		shards[i] = nil
+	}
+	require.NoError(t, ReconstructInterleaved(shards, originalCount))
+	assert.Equal(t, want, shards)
+}
+
+func TestInterleaverErrors(t *testing.T) {
+	_, err := NewInterleaver(10, 11, 64)
+	assert.Equal(t, ErrInvalidCounts, err)
+	// 2 recovery shares can't protect 3 codewords:
+	_, err = NewInterleaver(600, 2, 64, WithCodewordSize(256))
+	assert.True(t, errors.Is(err, ErrInvalidCounts))
+
+	il, err := NewInterleaver(10, 4, 64)
+	require.NoError(t, err)
+	defer il.Close()
+	assert.Equal(t, 1, il.Codewords())
+	_, err = il.Status(make([][]byte, 13))
+	assert.True(t, errors.Is(err, ErrInvalidCounts))
+}
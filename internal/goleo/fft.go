@@ -0,0 +1,124 @@
+package goleo
+
+// The bulk operations below work on whole buffers of symbols.
+// In GF(2^8) every byte is a symbol. In GF(2^16) buffers are processed in
+// blocks of 64 bytes, where symbol j (j < 32) of a block consists of the low
+// byte at offset j and the high byte at offset 32+j, as in leopard's SIMD code.
+
+// xorBytes sets x ^= y.
+func xorBytes(x, y []byte) {
+	y = y[:len(x)]
+	for i := range x {
+		x[i] ^= y[i]
+	}
+}
+
+// mulBytes sets x = y * exp(logM).
+func (f *field) mulBytes(x, y []byte, logM uint16) {
+	if f.mul8 != nil {
+		lut := &f.mul8[logM]
+		y = y[:len(x)]
+		for i := range x {
+			x[i] = lut[y[i]]
+		}
+		return
+	}
+	lut := f.mulLUT16(logM)
+	for off := 0; off+64 <= len(x); off += 64 {
+		xb, yb := x[off:off+64], y[off:off+64]
+		for j := 0; j < 32; j++ {
+			p := lut.mul(uint16(yb[j]) | uint16(yb[32+j])<<8)
+			xb[j], xb[32+j] = byte(p), byte(p>>8)
+		}
+	}
+}
+
+// mulAddBytes sets x ^= y * exp(logM).
+func (f *field) mulAddBytes(x, y []byte, logM uint16) {
+	if f.mul8 != nil {
+		lut := &f.mul8[logM]
+		y = y[:len(x)]
+		for i := range x {
+			x[i] ^= lut[y[i]]
+		}
+		return
+	}
+	lut := f.mulLUT16(logM)
+	for off := 0; off+64 <= len(x); off += 64 {
+		xb, yb := x[off:off+64], y[off:off+64]
+		for j := 0; j < 32; j++ {
+			p := lut.mul(uint16(yb[j]) | uint16(yb[32+j])<<8)
+			xb[j] ^= byte(p)
+			xb[32+j] ^= byte(p >> 8)
+		}
+	}
+}
+
+// lut16 holds the products of a fixed factor with every nibble value at each
+// of the four nibble positions of a GF(2^16) element.
+type lut16 [4][16]uint16
+
+func (t *lut16) mul(x uint16) uint16 {
+	return t[0][x&15] ^ t[1][x>>4&15] ^ t[2][x>>8&15] ^ t[3][x>>12]
+}
+
+// mulLUT16 returns the nibble tables for multiplying by exp(logM).
+// Multiplication by a constant is linear over GF(2), so only the products
+// with the 16 single bit elements need to be looked up.
+func (f *field) mulLUT16(logM uint16) *lut16 {
+	var t lut16
+	for i := range t {
+		for b := uint(0); b < 4; b++ {
+			t[i][1<<b] = f.mulLog(uint16(1)<<(4*uint(i)+b), logM)
+		}
+		for n := 3; n < 16; n++ {
+			if low := n & -n; low != n {
+				t[i][n] = t[i][n&(n-1)] ^ t[i][low]
+			}
+		}
+	}
+	return &t
+}
+
+// ifft2 is the inverse FFT butterfly: y ^= x; x ^= y * exp(logM).
+func (f *field) ifft2(x, y []byte, logM uint16) {
+	xorBytes(y, x)
+	if uint32(logM) != f.modulus {
+		f.mulAddBytes(x, y, logM)
+	}
+}
+
+// fft2 is the FFT butterfly: x ^= y * exp(logM); y ^= x.
+func (f *field) fft2(x, y []byte, logM uint16) {
+	if uint32(logM) != f.modulus {
+		f.mulAddBytes(x, y, logM)
+	}
+	xorBytes(y, x)
+}
+
+// ifft runs the decimation in time inverse FFT of size m on work, where only
+// the first mtrunc buffers are non-zero. The twiddle factor of a butterfly
+// starting at buffer r with distance dist is skew[skewOffset+r+dist].
+func (f *field) ifft(work [][]byte, mtrunc, m, skewOffset int) {
+	for dist := 1; dist < m; dist <<= 1 {
+		for r := 0; r < mtrunc; r += dist * 2 {
+			logM := f.skew[skewOffset+r+dist]
+			for i := r; i < r+dist; i++ {
+				f.ifft2(work[i], work[i+dist], logM)
+			}
+		}
+	}
+}
+
+// fft runs the decimation in time FFT of size m on work; only the first
+// mtrunc buffers of the output are computed.
+func (f *field) fft(work [][]byte, mtrunc, m, skewOffset int) {
+	for dist := m >> 1; dist != 0; dist >>= 1 {
+		for r := 0; r < mtrunc; r += dist * 2 {
+			logM := f.skew[skewOffset+r+dist]
+			for i := r; i < r+dist; i++ {
+				f.fft2(work[i], work[i+dist], logM)
+			}
+		}
+	}
+}
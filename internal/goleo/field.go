@@ -0,0 +1,217 @@
+package goleo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// field holds the lookup tables for GF(2^bits) in the representation used by
+// leopard: elements are given in a Cantor basis, which turns the additive FFT
+// into plain xor and table lookups.
+// Elements of both fields are stored as uint16.
+type field struct {
+	bits    uint
+	order   uint32 // number of elements
+	modulus uint32 // order - 1, also used as "multiply by zero" marker
+
+	exp, log []uint16
+	// skew holds the logarithms of the FFT twiddle factors:
+	skew []uint16
+	// logWalsh is the Walsh-Hadamard transform of log, used to compute the
+	// error locator polynomial while decoding:
+	logWalsh []uint16
+
+	// mul8 holds the full multiplication table of GF(2^8) indexed by the
+	// logarithm of one factor; it is nil for GF(2^16).
+	mul8 [][256]byte
+}
+
+var (
+	ff8Once, ff16Once sync.Once
+	// ff8Built and ff16Built are set once the tables are built, see
+	// Initialized8 and Initialized16:
+	ff8Built, ff16Built int32
+	ff8, ff16           *field
+	ff8Cantor           = []uint16{1, 214, 152, 146, 86, 200, 88, 230}
+	ff16Cantor          = []uint16{
+		0x0001, 0xACCA, 0x3C0E, 0x163E, 0xC582, 0xED2E, 0x914C, 0x4012,
+		0x6C98, 0x10D8, 0x6A72, 0xB900, 0xFDB8, 0xFB34, 0xFF38, 0x991E,
+	}
+)
+
+// field8 returns GF(2^8), building its tables on first use.
+func field8() *field {
+	ff8Once.Do(func() {
+		ff8 = newField(8, 0x11D, ff8Cantor)
+		ff8.mul8 = make([][256]byte, ff8.order)
+		for logM := range ff8.mul8 {
+			for x := range ff8.mul8[logM] {
+				ff8.mul8[logM][x] = byte(ff8.mulLog(uint16(x), uint16(logM)))
+			}
+		}
+		atomic.StoreInt32(&ff8Built, 1)
+	})
+	return ff8
+}
+
+// field16 returns GF(2^16), building its tables on first use.
+func field16() *field {
+	ff16Once.Do(func() {
+		ff16 = newField(16, 0x1002D, ff16Cantor)
+		atomic.StoreInt32(&ff16Built, 1)
+	})
+	return ff16
+}
+
+// Initialized8 reports whether the tables of GF(2^8) are built, which
+// happens on first use of the field.
+func Initialized8() bool {
+	return atomic.LoadInt32(&ff8Built) != 0
+}
+
+// Initialized16 reports whether the tables of GF(2^16) are built, which
+// happens on first use of the field.
+func Initialized16() bool {
+	return atomic.LoadInt32(&ff16Built) != 0
+}
+
+func newField(bits uint, poly uint32, cantorBasis []uint16) *field {
+	order := uint32(1) << bits
+	f := &field{
+		bits:     bits,
+		order:    order,
+		modulus:  order - 1,
+		exp:      make([]uint16, order),
+		log:      make([]uint16, order),
+		skew:     make([]uint16, order-1),
+		logWalsh: make([]uint16, order),
+	}
+	f.initLogExp(poly, cantorBasis)
+	f.initSkew()
+	copy(f.logWalsh, f.log)
+	f.logWalsh[0] = 0
+	f.fwht(f.logWalsh, int(order), int(order))
+	return f
+}
+
+func (f *field) initLogExp(poly uint32, cantorBasis []uint16) {
+	// exp temporarily holds the logarithms of the LFSR states:
+	state := uint32(1)
+	for i := uint32(0); i < f.modulus; i++ {
+		f.exp[state] = uint16(i)
+		state <<= 1
+		if state >= f.order {
+			state ^= poly
+		}
+	}
+	f.exp[0] = uint16(f.modulus)
+
+	// convert to the Cantor basis:
+	f.log[0] = 0
+	for i := uint(0); i < f.bits; i++ {
+		basis := cantorBasis[i]
+		width := uint32(1) << i
+		for j := uint32(0); j < width; j++ {
+			f.log[j+width] = f.log[j] ^ basis
+		}
+	}
+	for i := range f.log {
+		f.log[i] = f.exp[f.log[i]]
+	}
+	for i := range f.log {
+		f.exp[f.log[i]] = uint16(i)
+	}
+	f.exp[f.modulus] = f.exp[0]
+}
+
+func (f *field) initSkew() {
+	temp := make([]uint16, f.bits-1)
+	for i := uint(1); i < f.bits; i++ {
+		temp[i-1] = uint16(1) << i
+	}
+	for m := uint(0); m < f.bits-1; m++ {
+		step := uint32(1) << (m + 1)
+		f.skew[(1<<m)-1] = 0
+		for i := m; i < f.bits-1; i++ {
+			s := uint32(1) << (i + 1)
+			for j := uint32(1)<<m - 1; j < s; j += step {
+				f.skew[j+s] = f.skew[j] ^ temp[i]
+			}
+		}
+		temp[m] = uint16(f.modulus) - f.log[f.mulLog(temp[m], f.log[temp[m]^1])]
+		for i := m + 1; i < f.bits-1; i++ {
+			sum := f.addMod(f.log[temp[i]^1], temp[m])
+			temp[i] = f.mulLog(temp[i], sum)
+		}
+	}
+	for i := range f.skew {
+		f.skew[i] = f.log[f.skew[i]]
+	}
+}
+
+// addMod returns a+b mod modulus, where the result may be partially reduced,
+// i.e. modulus and 0 are both valid representations of zero.
+func (f *field) addMod(a, b uint16) uint16 {
+	sum := uint32(a) + uint32(b)
+	return uint16((sum + sum>>f.bits) & f.modulus)
+}
+
+// subMod returns a-b mod modulus, partially reduced like addMod.
+func (f *field) subMod(a, b uint16) uint16 {
+	dif := uint32(a) - uint32(b)
+	return uint16((dif + dif>>f.bits) & f.modulus)
+}
+
+// mulLog returns a*exp(logB).
+func (f *field) mulLog(a, logB uint16) uint16 {
+	if a == 0 {
+		return 0
+	}
+	return f.exp[f.addMod(f.log[a], logB)]
+}
+
+// fwht runs the (truncated) Walsh-Hadamard transform on data modulo modulus.
+// Only the first mtrunc elements of data may be non-zero.
+func (f *field) fwht(data []uint16, m, mtrunc int) {
+	for dist := 1; dist < m; dist <<= 1 {
+		for r := 0; r < mtrunc; r += dist * 2 {
+			for i := r; i < r+dist; i++ {
+				a, b := data[i], data[i+dist]
+				data[i], data[i+dist] = f.addMod(a, b), f.subMod(a, b)
+			}
+		}
+	}
+}
+
+// Field gives access to the arithmetic of one of leopard's fields, it backs
+// the exported gf package.
+type Field struct{ f *field }
+
+// FF8 returns leopard's GF(2^8).
+func FF8() Field {
+	return Field{field8()}
+}
+
+// FF16 returns leopard's GF(2^16).
+func FF16() Field {
+	return Field{field16()}
+}
+
+// Modulus returns the order of the multiplicative group, which is also the
+// logarithm used by leopard to mark a multiplication by zero.
+func (f Field) Modulus() uint16 { return uint16(f.f.modulus) }
+
+// Exp returns the element with the given logarithm.
+func (f Field) Exp(log uint16) uint16 { return f.f.exp[log] }
+
+// Log returns the logarithm of x, which must not be zero.
+func (f Field) Log(x uint16) uint16 { return f.f.log[x] }
+
+// MulLog returns x*Exp(log).
+func (f Field) MulLog(x, log uint16) uint16 { return f.f.mulLog(x, log) }
+
+// MulBytes sets x = y * Exp(log) symbol by symbol.
+func (f Field) MulBytes(x, y []byte, log uint16) { f.f.mulBytes(x, y, log) }
+
+// MulAddBytes sets x ^= y * Exp(log) symbol by symbol.
+func (f Field) MulAddBytes(x, y []byte, log uint16) { f.f.mulAddBytes(x, y, log) }
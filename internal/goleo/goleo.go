@@ -0,0 +1,297 @@
+// Package goleo is a pure Go port of the Leopard-RS codec
+// (https://github.com/catid/leopard).
+//
+// It mirrors the C API (leo_encode, leo_decode and the work counts) and
+// produces the same recovery data, so it can replace the C library in builds
+// without cgo. Like leopard, it uses GF(2^8) for codewords of up to 256
+// shares (after rounding to powers of two) and GF(2^16) above that.
+package goleo
+
+// Result mirrors LeopardResult of the C library.
+type Result int32
+
+const (
+	Success        Result = 0
+	NeedMoreData   Result = -1
+	TooMuchData    Result = -2
+	InvalidSize    Result = -3
+	InvalidCounts  Result = -4
+	InvalidInput   Result = -5
+	Platform       Result = -6
+	CallInitialize Result = -7
+)
+
+// maxShards is the order of the larger field, bounding the codeword size.
+const maxShards = 65536
+
+// EncodeWorkCount returns the number of work buffers Encode needs,
+// see leo_encode_work_count.
+func EncodeWorkCount(origCount, recoveryCount int) int {
+	if origCount == 1 {
+		return recoveryCount
+	}
+	if recoveryCount == 1 {
+		return 1
+	}
+	return nextPow2(recoveryCount) * 2
+}
+
+// DecodeWorkCount returns the number of work buffers Decode needs,
+// see leo_decode_work_count.
+func DecodeWorkCount(origCount, recoveryCount int) int {
+	if origCount == 1 || recoveryCount == 1 {
+		return origCount
+	}
+	return nextPow2(nextPow2(recoveryCount) + origCount)
+}
+
+// Encode computes recoveryCount recovery buffers for the origCount buffers
+// in data and writes them to the first recoveryCount buffers of work.
+// All buffers must hold at least bufferBytes bytes, which must be a multiple
+// of 64; work must consist of EncodeWorkCount(origCount, recoveryCount)
+// buffers. See leo_encode.
+func Encode(bufferBytes, origCount, recoveryCount int, data, work [][]byte) Result {
+	if res := checkArgs(bufferBytes, origCount, recoveryCount); res != Success {
+		return res
+	}
+	if len(data) != origCount || len(work) != EncodeWorkCount(origCount, recoveryCount) {
+		return InvalidCounts
+	}
+	data, ok := truncate(data, bufferBytes, false)
+	if !ok {
+		return InvalidInput
+	}
+	work, ok = truncate(work, bufferBytes, false)
+	if !ok {
+		return InvalidInput
+	}
+
+	if origCount == 1 {
+		for i := range work {
+			copy(work[i], data[0])
+		}
+		return Success
+	}
+	if recoveryCount == 1 {
+		copy(work[0], data[0])
+		for _, d := range data[1:] {
+			xorBytes(work[0], d)
+		}
+		return Success
+	}
+
+	m := nextPow2(recoveryCount)
+	f, res := fieldFor(m + origCount)
+	if res != Success {
+		return res
+	}
+
+	// work <- sum of IFFT(data[i:i+m]) over all chunks of m buffers, with the
+	// remaining m buffers of work used as scratch space:
+	for i := 0; i < m; i++ {
+		zero(work[i])
+	}
+	temp := work[m:]
+	for base := 0; base < origCount; base += m {
+		count := m
+		if origCount-base < m {
+			count = origCount - base
+		}
+		for i := 0; i < m; i++ {
+			if i < count {
+				copy(temp[i], data[base+i])
+			} else {
+				zero(temp[i])
+			}
+		}
+		f.ifft(temp, count, m, m-1+base)
+		for i := 0; i < m; i++ {
+			xorBytes(work[i], temp[i])
+		}
+	}
+
+	// work <- FFT(work), truncated to the recovery count:
+	f.fft(work, recoveryCount, m, -1)
+	return Success
+}
+
+// Decode recovers the missing buffers of orig, which must be nil in orig, from
+// the present buffers of orig and recovery (missing recovery buffers are nil
+// as well). The missing original buffer i is written to work[i]; work must
+// consist of DecodeWorkCount(origCount, recoveryCount) buffers of at least
+// bufferBytes bytes. See leo_decode.
+func Decode(bufferBytes, origCount, recoveryCount int, orig, recovery, work [][]byte) Result {
+	if res := checkArgs(bufferBytes, origCount, recoveryCount); res != Success {
+		return res
+	}
+	if len(orig) != origCount || len(recovery) != recoveryCount ||
+		len(work) != DecodeWorkCount(origCount, recoveryCount) {
+		return InvalidCounts
+	}
+	orig, ok := truncate(orig, bufferBytes, true)
+	if !ok {
+		return InvalidInput
+	}
+	recovery, ok = truncate(recovery, bufferBytes, true)
+	if !ok {
+		return InvalidInput
+	}
+	work, ok = truncate(work, bufferBytes, false)
+	if !ok {
+		return InvalidInput
+	}
+
+	lost, lostIndex := 0, 0
+	for i, o := range orig {
+		if o == nil {
+			lost++
+			lostIndex = i
+		}
+	}
+	got, gotIndex := 0, 0
+	for i, r := range recovery {
+		if r != nil {
+			got++
+			gotIndex = i
+		}
+	}
+	if got < lost {
+		return NeedMoreData
+	}
+	if lost == 0 {
+		for i, o := range orig {
+			copy(work[i], o)
+		}
+		return Success
+	}
+	if origCount == 1 {
+		copy(work[0], recovery[gotIndex])
+		return Success
+	}
+	if recoveryCount == 1 {
+		copy(work[lostIndex], recovery[0])
+		for _, o := range orig {
+			if o != nil {
+				xorBytes(work[lostIndex], o)
+			}
+		}
+		return Success
+	}
+
+	m := nextPow2(recoveryCount)
+	n := nextPow2(m + origCount)
+	f, res := fieldFor(m + origCount)
+	if res != Success {
+		return res
+	}
+
+	// error locator polynomial, evaluated via the Walsh-Hadamard transform:
+	errLocs := make([]uint16, f.order)
+	for i := 0; i < m; i++ {
+		if i >= recoveryCount || recovery[i] == nil {
+			errLocs[i] = 1
+		}
+	}
+	for i, o := range orig {
+		if o == nil {
+			errLocs[m+i] = 1
+		}
+	}
+	f.fwht(errLocs, int(f.order), m+origCount)
+	for i := range errLocs {
+		errLocs[i] = uint16(uint32(errLocs[i]) * uint32(f.logWalsh[i]) % f.modulus)
+	}
+	f.fwht(errLocs, int(f.order), int(f.order))
+
+	// work <- (recovery || orig) scaled by the error locator:
+	for i := 0; i < m; i++ {
+		if i < recoveryCount && recovery[i] != nil {
+			f.mulBytes(work[i], recovery[i], errLocs[i])
+		} else {
+			zero(work[i])
+		}
+	}
+	for i, o := range orig {
+		if o != nil {
+			f.mulBytes(work[m+i], o, errLocs[m+i])
+		} else {
+			zero(work[m+i])
+		}
+	}
+	for i := m + origCount; i < n; i++ {
+		zero(work[i])
+	}
+
+	// work <- FFT(FormalDerivative(IFFT(work))):
+	f.ifft(work, m+origCount, n, -1)
+	for i := 1; i < n; i++ {
+		width := ((i ^ (i - 1)) + 1) >> 1
+		for j := 0; j < width; j++ {
+			xorBytes(work[i-width+j], work[i+j])
+		}
+	}
+	f.fft(work, m+origCount, n, -1)
+
+	// reveal the erasures:
+	for i, o := range orig {
+		if o == nil {
+			f.mulBytes(work[i], work[m+i], uint16(f.modulus)-errLocs[m+i])
+		}
+	}
+	return Success
+}
+
+func checkArgs(bufferBytes, origCount, recoveryCount int) Result {
+	if bufferBytes <= 0 || bufferBytes%64 != 0 {
+		return InvalidSize
+	}
+	if origCount <= 0 || recoveryCount <= 0 || recoveryCount > origCount {
+		return InvalidCounts
+	}
+	if origCount+recoveryCount > maxShards {
+		return TooMuchData
+	}
+	return Success
+}
+
+// fieldFor returns the field leopard uses for a codeword of count buffers.
+func fieldFor(count int) (*field, Result) {
+	switch n := nextPow2(count); {
+	case n <= 1<<8:
+		return field8(), Success
+	case n <= 1<<16:
+		return field16(), Success
+	default:
+		return nil, TooMuchData
+	}
+}
+
+// truncate returns views on the first bufferBytes bytes of bufs.
+// nil buffers are passed through if allowNil is set.
+func truncate(bufs [][]byte, bufferBytes int, allowNil bool) ([][]byte, bool) {
+	views := make([][]byte, len(bufs))
+	for i, b := range bufs {
+		if b == nil && allowNil {
+			continue
+		}
+		if len(b) < bufferBytes {
+			return nil, false
+		}
+		views[i] = b[:bufferBytes]
+	}
+	return views, true
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,103 @@
+package goleo
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldTables(t *testing.T) {
+	for _, f := range []*field{field8(), field16()} {
+		// exp and log are inverse to each other:
+		for x := uint32(1); x < f.order; x++ {
+			require.Equal(t, uint16(x), f.exp[f.log[x]])
+		}
+		// multiplying with the inverse yields 1:
+		for _, x := range []uint16{1, 2, 3, 0x55, uint16(f.modulus)} {
+			inv := f.mulLog(1, uint16(f.modulus)-f.log[x])
+			assert.Equal(t, uint16(1), f.mulLog(x, f.log[inv]))
+		}
+	}
+	assert.True(t, Initialized8())
+	assert.True(t, Initialized16())
+}
+
+func TestMulBytesMatchesMulLog(t *testing.T) {
+	ff8, ff16 := field8(), field16()
+	x := make([]byte, 128)
+	y := make([]byte, 128)
+	rand.Read(y)
+
+	ff8.mulBytes(x, y, 77)
+	for i := range x {
+		assert.Equal(t, ff8.mulLog(uint16(y[i]), 77), uint16(x[i]))
+	}
+	ff16.mulBytes(x, y, 12345)
+	for off := 0; off < len(x); off += 64 {
+		for j := 0; j < 32; j++ {
+			a := uint16(y[off+j]) | uint16(y[off+32+j])<<8
+			p := uint16(x[off+j]) | uint16(x[off+32+j])<<8
+			assert.Equal(t, ff16.mulLog(a, 12345), p)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, counts := range [][2]int{{1, 1}, {3, 1}, {2, 2}, {100, 37}, {128, 128}, {300, 200}} {
+		origCount, recoveryCount := counts[0], counts[1]
+		t.Run(fmt.Sprintf("%d+%d", origCount, recoveryCount), func(t *testing.T) {
+			const bufferBytes = 128
+			data := make([][]byte, origCount)
+			for i := range data {
+				data[i] = make([]byte, bufferBytes)
+				rand.Read(data[i])
+			}
+			work := make([][]byte, EncodeWorkCount(origCount, recoveryCount))
+			for i := range work {
+				work[i] = make([]byte, bufferBytes)
+			}
+			require.Equal(t, Success, Encode(bufferBytes, origCount, recoveryCount, data, work))
+
+			orig := make([][]byte, origCount)
+			copy(orig, data)
+			recovery := work[:recoveryCount]
+			lost := rand.Perm(origCount)[:recoveryCount]
+			for _, i := range lost {
+				orig[i] = nil
+			}
+			decodeWork := make([][]byte, DecodeWorkCount(origCount, recoveryCount))
+			for i := range decodeWork {
+				decodeWork[i] = make([]byte, bufferBytes)
+			}
+			require.Equal(t, Success, Decode(bufferBytes, origCount, recoveryCount, orig, recovery, decodeWork))
+			for _, i := range lost {
+				assert.Equal(t, data[i], decodeWork[i])
+			}
+
+			// one more loss can't be recovered:
+			if recoveryCount < origCount {
+				orig[rand.Intn(origCount)] = nil
+				for i := range orig {
+					if orig[i] != nil {
+						orig[i] = nil
+						break
+					}
+				}
+				assert.Equal(t, NeedMoreData, Decode(bufferBytes, origCount, recoveryCount, orig, recovery, decodeWork))
+			}
+		})
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	data := [][]byte{make([]byte, 64), make([]byte, 64)}
+	work := [][]byte{make([]byte, 64), make([]byte, 64), make([]byte, 64), make([]byte, 64)}
+	assert.Equal(t, InvalidSize, Encode(65, 2, 2, data, work))
+	assert.Equal(t, InvalidCounts, Encode(64, 2, 3, data, work))
+	assert.Equal(t, InvalidCounts, Encode(64, 2, 2, data, work[:3]))
+	assert.Equal(t, InvalidInput, Encode(128, 2, 2, data, work))
+	assert.Equal(t, TooMuchData, Encode(64, 40000, 30000, make([][]byte, 40000), nil))
+}
@@ -0,0 +1,130 @@
+package leopard
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// MemoryStats describes the C memory allocated by the package, see MemStats.
+// Without cgo, no C memory is allocated and all counters stay 0.
+type MemoryStats struct {
+	// Allocs and Bytes are the number and total size of the C allocations
+	// currently held, e.g. by open Codecs and unfreed ShardBuffers.
+	Allocs int
+	Bytes  int64
+	// TotalAllocs and TotalBytes count all C allocations ever made.
+	TotalAllocs int64
+	TotalBytes  int64
+
+	// Live lists the C allocations currently held, oldest first, if
+	// SetMemDebug is enabled. Allocations made before it was enabled are
+	// missing.
+	Live []Allocation
+}
+
+// Allocation describes a live C allocation recorded in debug mode.
+type Allocation struct {
+	Bytes int
+	// Stack is the stack trace of the goroutine which allocated the memory.
+	Stack string
+}
+
+// cMemory tracks the live C allocations by address.
+var cMemory struct {
+	sync.Mutex
+	debug  bool
+	sizes  map[uintptr]int
+	stacks map[uintptr]allocationStack
+	seq    int64
+	stats  MemoryStats
+}
+
+type allocationStack struct {
+	p     uintptr
+	seq   int64
+	stack string
+}
+
+// MemStats reports the C memory currently held by the package.
+// Tests can assert that no memory leaks, e.g. by comparing the Allocs
+// before and after encoding.
+func MemStats() MemoryStats {
+	cMemory.Lock()
+	defer cMemory.Unlock()
+	stats := cMemory.stats
+	stats.Live = nil
+	live := make([]allocationStack, 0, len(cMemory.stacks))
+	for _, s := range cMemory.stacks {
+		live = append(live, s)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].seq < live[j].seq })
+	for _, s := range live {
+		stats.Live = append(stats.Live, Allocation{Bytes: cMemory.sizes[s.p], Stack: s.stack})
+	}
+	return stats
+}
+
+// SetMemDebug enables or disables recording the stack trace of every C
+// allocation, which MemStats reports for the allocations still held.
+// Recording stacks is slow; it is meant to find leaks in tests.
+func SetMemDebug(on bool) {
+	cMemory.Lock()
+	defer cMemory.Unlock()
+	cMemory.debug = on
+	if !on {
+		cMemory.stacks = nil
+	}
+}
+
+// trackAlloc records a C allocation of size bytes at p.
+func trackAlloc(p uintptr, size int) {
+	if p == 0 {
+		return
+	}
+	var stack string
+	if memDebug() {
+		buf := make([]byte, 4096)
+		stack = string(buf[:runtime.Stack(buf, false)])
+	}
+	cMemory.Lock()
+	defer cMemory.Unlock()
+	if cMemory.sizes == nil {
+		cMemory.sizes = make(map[uintptr]int)
+	}
+	cMemory.sizes[p] = size
+	cMemory.stats.Allocs++
+	cMemory.stats.Bytes += int64(size)
+	cMemory.stats.TotalAllocs++
+	cMemory.stats.TotalBytes += int64(size)
+	if cMemory.debug && stack != "" {
+		if cMemory.stacks == nil {
+			cMemory.stacks = make(map[uintptr]allocationStack)
+		}
+		cMemory.seq++
+		cMemory.stacks[p] = allocationStack{p: p, seq: cMemory.seq, stack: stack}
+	}
+}
+
+// trackFree records that the C allocation at p was freed.
+func trackFree(p uintptr) {
+	if p == 0 {
+		return
+	}
+	cMemory.Lock()
+	defer cMemory.Unlock()
+	size, ok := cMemory.sizes[p]
+	if !ok {
+		return
+	}
+	delete(cMemory.sizes, p)
+	delete(cMemory.stacks, p)
+	cMemory.stats.Allocs--
+	cMemory.stats.Bytes -= int64(size)
+}
+
+func memDebug() bool {
+	cMemory.Lock()
+	defer cMemory.Unlock()
+	return cMemory.debug
+}
@@ -0,0 +1,72 @@
+//go:build !cgo
+// +build !cgo
+
+package leopard
+
+import "unsafe"
+
+// Without cgo the C library can't be linked and the pure Go implementation
+// of leopard is used instead.
+
+// Leopardresult is the result code of a leopard operation, see LeopardError.
+type Leopardresult int32
+
+// result codes returned by leopard:
+const (
+	LeopardSuccess        Leopardresult = 0
+	LeopardNeedmoredata   Leopardresult = -1
+	LeopardToomuchdata    Leopardresult = -2
+	LeopardInvalidsize    Leopardresult = -3
+	LeopardInvalidcounts  Leopardresult = -4
+	LeopardInvalidinput   Leopardresult = -5
+	LeopardPlatform       Leopardresult = -6
+	LeopardCallinitialize Leopardresult = -7
+)
+
+// resultString returns the same descriptions as leo_result_string.
+func resultString(errCode Leopardresult) string {
+	switch errCode {
+	case LeopardSuccess:
+		return "Operation succeeded"
+	case LeopardNeedmoredata:
+		return "Not enough recovery data received"
+	case LeopardToomuchdata:
+		return "Buffer counts are too high"
+	case LeopardInvalidsize:
+		return "Buffer size must be a multiple of 64 bytes"
+	case LeopardInvalidcounts:
+		return "Invalid counts provided"
+	case LeopardInvalidinput:
+		return "A function parameter was invalid"
+	case LeopardPlatform:
+		return "Platform is unsupported"
+	case LeopardCallinitialize:
+		return "Call leo_init() first"
+	default:
+		return "Unknown"
+	}
+}
+
+// initLibrary is a no-op: the tables of the pure Go implementation are
+// built on first use.
+func initLibrary() error {
+	return nil
+}
+
+func newBackend(origCount, recoveryCount int, layout shareLayout, o options) (backend, error) {
+	return newGoBackend(origCount, recoveryCount, layout), nil
+}
+
+// allocShardMemory allocates count shares of size bytes for a ShardBuffer,
+// which are just Go memory without cgo.
+func allocShardMemory(count, size int) (unsafe.Pointer, [][]byte) {
+	mem := make([]byte, count*size)
+	shares := make([][]byte, count)
+	for i := range shares {
+		shares[i] = mem[i*size : (i+1)*size : (i+1)*size]
+	}
+	return unsafe.Pointer(&mem[0]), shares
+}
+
+// freeShardMemory leaves the memory to the garbage collector.
+func freeShardMemory(mem unsafe.Pointer) {}
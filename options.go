@@ -0,0 +1,76 @@
+package leopard
+
+// Option configures a Codec or the package level functions creating one
+// internally.
+type Option func(*options)
+
+type options struct {
+	padding      bool
+	pureGo       bool
+	codewordSize int
+	goroutines   int
+	threads      int
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithPadding allows shares of any (uniform) size instead of only multiples
+// of 64 bytes. Shares are zero padded to the next multiple of 64 bytes before
+// they are passed to leopard and the results are trimmed to the share size
+// again, i.e. recovery shares have the same size as the original shares.
+// For codewords encoded in GF(2^16) (more than 256 shares after rounding the
+// recovery count and then the total to powers of two), the share size must
+// be even as the symbols are 2 bytes wide.
+func WithPadding() Option {
+	return func(o *options) {
+		o.padding = true
+	}
+}
+
+// WithPureGo makes a Codec use the pure Go implementation of leopard instead
+// of the C library. It produces the same recovery shares, but is slower.
+// Builds without cgo always use the pure Go implementation.
+func WithPureGo() Option {
+	return func(o *options) {
+		o.pureGo = true
+	}
+}
+
+// WithCodewordSize limits the number of shares (original plus recovery) per
+// codeword of an Interleaver to n, counting the recovery shares rounded up to
+// the next power of two as leopard does internally. For example, n = 256
+// encodes all codewords in the faster GF(2^8). It is ignored by a Codec.
+func WithCodewordSize(n int) Option {
+	return func(o *options) {
+		o.codewordSize = n
+	}
+}
+
+// WithGoroutines spreads every encode and decode of a Codec across up to n
+// goroutines: as every byte offset of the shares is a codeword on its own,
+// the shares are split into column stripes of multiples of 64 bytes which
+// are processed concurrently. The result is identical to processing the
+// shares as a whole. Shares of only 64 bytes can't be split. n <= 1 (the
+// default) processes the shares on the calling goroutine.
+func WithGoroutines(n int) Option {
+	return func(o *options) {
+		o.goroutines = n
+	}
+}
+
+// WithThreads bounds the number of threads the C library runs a single
+// encode or decode of a Codec on, and the number of codewords its batch
+// calls process at once, to n, overriding SetThreads. n <= 0 uses the bound
+// set by SetThreads.
+// With WithGoroutines, every goroutine runs the C library on up to n threads.
+func WithThreads(n int) Option {
+	return func(o *options) {
+		o.threads = n
+	}
+}
@@ -0,0 +1,65 @@
+package leopard
+
+// shareLayout maps shares of shardSize bytes to the buffers of bufferBytes
+// passed to leopard, which differ only if padding is enabled.
+type shareLayout struct {
+	shardSize   int
+	bufferBytes int
+	// splitTail is set if the last partial 64 byte block of a share is
+	// split between low and high bytes, see pad:
+	splitTail bool
+}
+
+func newShareLayout(origCount, recoveryCount, shardSize int) shareLayout {
+	bufferBytes := (shardSize + 63) / 64 * 64
+	return shareLayout{
+		shardSize:   shardSize,
+		bufferBytes: bufferBytes,
+		splitTail:   bufferBytes != shardSize && wideSymbols(origCount, recoveryCount),
+	}
+}
+
+// padded reports whether shares have to be padded.
+func (l shareLayout) padded() bool { return l.shardSize != l.bufferBytes }
+
+// pad copies share into the zeroed buffer buf passed to leopard.
+// Usually the share is copied to the start of buf, leaving the padding at the
+// end. This relies on every byte being a symbol on its own as in GF(2^8):
+// zero symbols encode to zero recovery symbols, so the recovery shares can be
+// trimmed to the share size without losing anything.
+// In GF(2^16), symbol j of a 64 byte block consists of the bytes j (low) and
+// 32+j (high) though. There the last partial block of a share (of 2h bytes) is
+// split in halves filling the low and high bytes of its first h symbols, so
+// that the padding consists of whole zero symbols again.
+func (l shareLayout) pad(buf, share []byte) {
+	if !l.splitTail {
+		copy(buf, share)
+		return
+	}
+	tail := l.shardSize / 64 * 64
+	half := (l.shardSize - tail) / 2
+	copy(buf, share[:tail+half])
+	copy(buf[tail+32:], share[tail+half:])
+}
+
+// trim reverses pad, copying the share held in buf to share.
+func (l shareLayout) trim(share, buf []byte) {
+	if !l.splitTail {
+		copy(share, buf)
+		return
+	}
+	tail := l.shardSize / 64 * 64
+	half := (l.shardSize - tail) / 2
+	copy(share[:tail+half], buf)
+	copy(share[tail+half:], buf[tail+32:tail+32+half])
+}
+
+// wideSymbols reports whether leopard uses GF(2^16) for the codeword shape,
+// i.e. whether its symbols are 2 bytes wide.
+func wideSymbols(origCount, recoveryCount int) bool {
+	if origCount == 1 || recoveryCount == 1 {
+		// copying and xor work on single bytes:
+		return false
+	}
+	return nextPow2(nextPow2(recoveryCount)+origCount) > 256
+}
@@ -0,0 +1,25 @@
+//go:build cgo && go1.21
+// +build cgo,go1.21
+
+package leopard
+
+import "runtime"
+
+// sharePinner pins Go allocated shares for the duration of a call into C, so
+// leopard can read them in place instead of from a copy in C memory.
+// Memory passed to C may hold pointers to pinned Go memory, which keeps the
+// backend's input pointer arrays clean under cgocheck.
+type sharePinner struct {
+	p runtime.Pinner
+}
+
+// pin pins share and reports whether it may be passed to C as is.
+func (p *sharePinner) pin(share []byte) bool {
+	p.p.Pin(&share[0])
+	return true
+}
+
+// unpin unpins all shares pinned since the last call.
+func (p *sharePinner) unpin() {
+	p.p.Unpin()
+}
@@ -0,0 +1,15 @@
+//go:build cgo && !go1.21
+// +build cgo,!go1.21
+
+package leopard
+
+// sharePinner can't pin Go memory before Go 1.21 (runtime.Pinner), so shares
+// are always copied to C memory before they are passed to leopard.
+type sharePinner struct{}
+
+// pin reports false as share can't be pinned.
+func (p *sharePinner) pin(share []byte) bool {
+	return false
+}
+
+func (p *sharePinner) unpin() {}
@@ -0,0 +1,60 @@
+//go:build cgo && go1.21
+// +build cgo,go1.21
+
+package leopard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedInputs(t *testing.T) {
+	const originalCount = 16
+	const bufferBytes = 256
+
+	c, err := NewCodec(originalCount, originalCount, bufferBytes)
+	require.NoError(t, err)
+	defer c.Close()
+
+	data := make([][]byte, originalCount)
+	for i := range data {
+		data[i] = make([]byte, bufferBytes)
+		checkedRandBytes(data[i])
+	}
+	parity, err := c.Encode(data)
+	require.NoError(t, err)
+	want, err := Encode(data, WithPureGo())
+	require.NoError(t, err)
+	assert.Equal(t, want, parity)
+
+	orig := deepCopy(data)
+	orig[3], orig[7] = nil, nil
+	recovered, err := c.Recover(orig, parity)
+	require.NoError(t, err)
+	assert.Equal(t, data, recovered[:originalCount])
+
+	// the shares were passed to leopard in place, the C buffers for copies
+	// were never allocated:
+	b := c.b.(*cBackend)
+	assert.Nil(t, b.orig)
+	assert.Nil(t, b.recovery)
+	for _, p := range append(b.origIn, b.recoveryIn...) {
+		assert.True(t, p == nil)
+	}
+
+	// padded shares are copied:
+	padded, err := NewCodec(originalCount, originalCount, bufferBytes-1, WithPadding())
+	require.NoError(t, err)
+	defer padded.Close()
+	short := make([][]byte, originalCount)
+	for i := range short {
+		short[i] = data[i][:bufferBytes-1]
+	}
+	_, err = padded.Encode(short)
+	require.NoError(t, err)
+	b = padded.b.(*cBackend)
+	assert.Len(t, b.orig, originalCount)
+	assert.Nil(t, b.recovery)
+}
@@ -0,0 +1,201 @@
+package leopard
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// ShardBuffer holds a number of equally sized shares in a single block of
+// memory allocated outside of the Go heap (in C memory, unless the package
+// is built without cgo) and exposes them as Go slices.
+// Shares within a ShardBuffer are passed to leopard as is instead of being
+// copied to the C library's buffers first (unless padding is needed), and
+// Codec.EncodeBuffer and Codec.DecodeBuffer let leopard write its results
+// straight into a ShardBuffer.
+//
+// The memory is released by Free. Once a ShardBuffer becomes unreachable it
+// is released by a finalizer as well, so the ShardBuffer must be kept
+// reachable (e.g. with runtime.KeepAlive) for as long as its shares are
+// used: the garbage collector doesn't know that the shares point into its
+// memory.
+type ShardBuffer struct {
+	mu     sync.Mutex
+	mem    unsafe.Pointer
+	shares [][]byte
+	size   int
+	// span is the memory range of all shares, see inShardMemory:
+	span span
+}
+
+// NewShardBuffer allocates a zeroed ShardBuffer of count shares of size bytes.
+func NewShardBuffer(count, size int) (*ShardBuffer, error) {
+	if count < 1 {
+		return nil, ErrInvalidCounts
+	}
+	if size < 1 {
+		return nil, ErrInvalidSize
+	}
+	return newShardBuffer(count, size), nil
+}
+
+func newShardBuffer(count, size int) *ShardBuffer {
+	mem, shares := allocShardMemory(count, size)
+	b := &ShardBuffer{
+		mem:    mem,
+		shares: shares,
+		size:   size,
+		span:   span{start: uintptr(mem), end: uintptr(mem) + uintptr(count*size)},
+	}
+	registerShardMemory(b.span)
+	runtime.SetFinalizer(b, (*ShardBuffer).Free)
+	return b
+}
+
+// Shares returns the shares held by the ShardBuffer, nil once it is freed.
+// The shares must not be used after the ShardBuffer is freed.
+func (b *ShardBuffer) Shares() [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.shares
+}
+
+// Len returns the number of shares held by the ShardBuffer, 0 once it is
+// freed.
+func (b *ShardBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.shares)
+}
+
+// Size returns the size of a single share in bytes.
+func (b *ShardBuffer) Size() int { return b.size }
+
+// Free releases the memory of the ShardBuffer. Calling Free more than once
+// is a no-op.
+func (b *ShardBuffer) Free() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.mem == nil {
+		return
+	}
+	unregisterShardMemory(b.span)
+	freeShardMemory(b.mem)
+	b.mem, b.shares = nil, nil
+	runtime.SetFinalizer(b, nil)
+}
+
+// shardMemory tracks the memory of all live ShardBuffers sorted by address,
+// see inShardMemory.
+var shardMemory struct {
+	sync.RWMutex
+	spans []span
+}
+
+func registerShardMemory(s span) {
+	shardMemory.Lock()
+	defer shardMemory.Unlock()
+	i := sort.Search(len(shardMemory.spans), func(i int) bool { return shardMemory.spans[i].start >= s.start })
+	shardMemory.spans = append(shardMemory.spans, span{})
+	copy(shardMemory.spans[i+1:], shardMemory.spans[i:])
+	shardMemory.spans[i] = s
+}
+
+func unregisterShardMemory(s span) {
+	shardMemory.Lock()
+	defer shardMemory.Unlock()
+	i := sort.Search(len(shardMemory.spans), func(i int) bool { return shardMemory.spans[i].start >= s.start })
+	if i < len(shardMemory.spans) && shardMemory.spans[i].start == s.start {
+		shardMemory.spans = append(shardMemory.spans[:i], shardMemory.spans[i+1:]...)
+	}
+}
+
+// inShardMemory reports whether share lies within the memory of a live
+// ShardBuffer, i.e. outside of the Go heap.
+func inShardMemory(share []byte) bool {
+	if len(share) == 0 {
+		return false
+	}
+	start := uintptr(unsafe.Pointer(&share[0]))
+	end := start + uintptr(len(share))
+	shardMemory.RLock()
+	defer shardMemory.RUnlock()
+	// the last span starting at or before share is the only candidate:
+	i := sort.Search(len(shardMemory.spans), func(i int) bool { return shardMemory.spans[i].start > start }) - 1
+	return i >= 0 && end <= shardMemory.spans[i].end
+}
+
+// EncodeBuffer computes the recovery shares for data like Encode, but
+// returns them in a ShardBuffer, which the caller has to Free.
+// Unless padding is needed or the Codec uses several goroutines, leopard
+// writes the recovery shares straight into the ShardBuffer, using the
+// Codec's own buffers as scratch space.
+func (c *Codec) EncodeBuffer(data [][]byte) (*ShardBuffer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrCodecClosed
+	}
+	if err := c.checkShares(OpEncode, data, c.origCount, 0, false); err != nil {
+		return nil, err
+	}
+	if c.layout.padded() || c.parallel() {
+		buf := newShardBuffer(c.recoveryCount, c.shardSize)
+		if err := c.encodeInto(data, buf.shares); err != nil {
+			buf.Free()
+			return nil, err
+		}
+		return buf, nil
+	}
+	buf := newShardBuffer(c.recoveryCount, c.bufferBytes)
+	if err := c.resultToErr(OpEncode, c.b.encodeTo(data, buf.shares)); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// DecodeBuffer recovers the missing original shares like Recover, but
+// returns the original shares (only) in a ShardBuffer, which the caller has
+// to Free. Missing shares have to be nil or empty.
+// Unless padding is needed or the Codec uses several goroutines, leopard
+// writes the missing shares straight into the ShardBuffer, using the Codec's
+// own buffers as scratch space; the present original shares are copied.
+func (c *Codec) DecodeBuffer(orig, recovery [][]byte) (*ShardBuffer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrCodecClosed
+	}
+	if err := c.checkShares(OpDecode, orig, c.origCount, 0, true); err != nil {
+		return nil, err
+	}
+	if err := c.checkShares(OpDecode, recovery, c.recoveryCount, c.origCount, true); err != nil {
+		return nil, err
+	}
+	avail := availabilityOf(orig, recovery)
+	if err := c.checkRecoverable(avail); err != nil {
+		return nil, err
+	}
+
+	if countMissing(orig) == 0 || c.layout.padded() || c.parallel() {
+		buf := newShardBuffer(c.origCount, c.shardSize)
+		copyPresent(buf.shares, orig)
+		if countMissing(orig) > 0 {
+			if err := c.decodeOriginals(orig, recovery, avail, buf.shares); err != nil {
+				buf.Free()
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	buf := newShardBuffer(c.origCount, c.bufferBytes)
+	if err := c.resultToErr(OpDecode, c.b.decodeTo(orig, recovery, avail, buf.shares)); err != nil {
+		buf.Free()
+		return nil, err
+	}
+	// the entries of present shares only hold scratch data of leopard:
+	copyPresent(buf.shares, orig)
+	return buf, nil
+}
@@ -0,0 +1,91 @@
+package leopard
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardBuffer(t *testing.T) {
+	buf, err := NewShardBuffer(4, 100)
+	require.NoError(t, err)
+	require.Equal(t, 4, buf.Len())
+	require.Equal(t, 100, buf.Size())
+	for _, s := range buf.Shares() {
+		assert.Equal(t, make([]byte, 100), s)
+		assert.True(t, inShardMemory(s))
+		assert.True(t, inShardMemory(s[10:20]))
+	}
+	assert.False(t, inShardMemory(make([]byte, 100)))
+
+	buf.Free()
+	buf.Free()
+	assert.Equal(t, 0, buf.Len())
+	assert.Nil(t, buf.Shares())
+
+	_, err = NewShardBuffer(0, 64)
+	assert.Equal(t, ErrInvalidCounts, err)
+	_, err = NewShardBuffer(1, 0)
+	assert.Equal(t, ErrInvalidSize, err)
+}
+
+func TestEncodeDecodeBuffer(t *testing.T) {
+	const originalCount = 64
+	const recoveryCount = 32
+
+	for _, tc := range []struct {
+		name      string
+		shardSize int
+		opts      []Option
+	}{
+		{"default", 128, nil},
+		{"pure Go", 128, []Option{WithPureGo()}},
+		{"padding", 100, []Option{WithPadding()}},
+		{"goroutines", 256, []Option{WithGoroutines(2)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewCodec(originalCount, recoveryCount, tc.shardSize, tc.opts...)
+			require.NoError(t, err)
+			defer c.Close()
+
+			// the data shares are passed to leopard as is:
+			data, err := NewShardBuffer(originalCount, tc.shardSize)
+			require.NoError(t, err)
+			defer data.Free()
+			for _, s := range data.Shares() {
+				checkedRandBytes(s)
+			}
+			want, err := c.Encode(deepCopy(data.Shares()))
+			require.NoError(t, err)
+
+			before := MemStats().Bytes
+			parity, err := c.EncodeBuffer(data.Shares())
+			require.NoError(t, err)
+			defer parity.Free()
+			require.Equal(t, recoveryCount, parity.Len())
+			// leopard's scratch space isn't part of the ShardBuffer:
+			assert.LessOrEqual(t, MemStats().Bytes-before, int64(recoveryCount*tc.shardSize))
+			assert.Equal(t, want, parity.Shares())
+			// Encode accepts ShardBuffers as well:
+			got, err := c.Encode(data.Shares())
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			orig := append([][]byte{}, data.Shares()...)
+			for i := 0; i < recoveryCount; i++ {
+				orig[2*i] = nil
+			}
+			decoded, err := c.DecodeBuffer(orig, parity.Shares())
+			require.NoError(t, err)
+			defer decoded.Free()
+			assert.Equal(t, data.Shares(), decoded.Shares())
+
+			recovered, err := c.Recover(orig, parity.Shares())
+			require.NoError(t, err)
+			assert.Equal(t, data.Shares(), recovered[:originalCount])
+			runtime.KeepAlive(data)
+		})
+	}
+}
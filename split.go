@@ -0,0 +1,63 @@
+package leopard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrShortData = errors.New("not enough data in shares")
+
+// Split splits blob into dataShards equally sized shares which can be passed
+// to Encode. The share size is the smallest multiple of 64 bytes which fits
+// len(blob) into dataShards shares; the tail of the last shares is zero padded.
+// The shares do not alias blob.
+// Keep len(blob) around to trim the padding again when calling Join.
+func Split(blob []byte, dataShards int) ([][]byte, error) {
+	if dataShards <= 0 {
+		return nil, ErrInvalidCounts
+	}
+	if len(blob) == 0 {
+		return nil, ErrShortData
+	}
+	perShard := (len(blob) + dataShards - 1) / dataShards
+	shardSize := (perShard + 63) / 64 * 64
+
+	buf := make([]byte, shardSize*dataShards)
+	copy(buf, blob)
+	shards := make([][]byte, dataShards)
+	for i := range shards {
+		shards[i] = buf[i*shardSize : (i+1)*shardSize : (i+1)*shardSize]
+	}
+	return shards, nil
+}
+
+// Join writes the first size bytes of the concatenated shares to w, i.e. it
+// reverses Split when passed the (recovered) data shares and the length of
+// the original blob. Only the shares needed to produce size bytes have to be
+// present.
+func Join(w io.Writer, shards [][]byte, size int) error {
+	if size < 0 {
+		return ErrInvalidInput
+	}
+	for i, s := range shards {
+		if size == 0 {
+			return nil
+		}
+		if len(s) == 0 {
+			return fmt.Errorf("%w: share %d is missing", ErrShortData, i)
+		}
+		if len(s) > size {
+			s = s[:size]
+		}
+		n, err := w.Write(s)
+		if err != nil {
+			return err
+		}
+		size -= n
+	}
+	if size > 0 {
+		return ErrShortData
+	}
+	return nil
+}
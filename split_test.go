@@ -0,0 +1,81 @@
+package leopard
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitJoin(t *testing.T) {
+	tcs := []struct {
+		name       string
+		size       int
+		dataShards int
+		shardSize  int
+	}{
+		{"single byte", 1, 4, 64},
+		{"exact fit", 4 * 64, 4, 64},
+		{"one byte over", 4*64 + 1, 4, 128},
+		{"uneven", 10000, 7, 1472},
+		{"one share", 100, 1, 128},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			blob := make([]byte, tc.size)
+			rand.Read(blob)
+
+			shards, err := Split(blob, tc.dataShards)
+			require.NoError(t, err)
+			require.Equal(t, tc.dataShards, len(shards))
+			for _, s := range shards {
+				assert.Equal(t, tc.shardSize, len(s))
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, Join(&buf, shards, tc.size))
+			assert.Equal(t, blob, buf.Bytes())
+		})
+	}
+}
+
+func TestSplitEncodeRecoverJoin(t *testing.T) {
+	blob := make([]byte, 12345)
+	rand.Read(blob)
+
+	shards, err := Split(blob, 32)
+	require.NoError(t, err)
+	parity, err := EncodeWithRecovery(shards, 8)
+	require.NoError(t, err)
+
+	for i := 0; i < 8; i++ {
+		shards[i*3] = nil
+	}
+	all := append(shards, parity...)
+	require.NoError(t, ReconstructData(all, 32))
+
+	var buf bytes.Buffer
+	require.NoError(t, Join(&buf, all[:32], len(blob)))
+	assert.Equal(t, blob, buf.Bytes())
+}
+
+func TestSplitJoinErrors(t *testing.T) {
+	_, err := Split(nil, 4)
+	assert.Equal(t, ErrShortData, err)
+	_, err = Split([]byte{1}, 0)
+	assert.Equal(t, ErrInvalidCounts, err)
+
+	shards, err := Split(make([]byte, 300), 4)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	assert.Equal(t, ErrShortData, Join(&buf, shards, 4*128+1))
+
+	// missing shares are only a problem if they are needed:
+	shards[3] = nil
+	buf.Reset()
+	assert.NoError(t, Join(&buf, shards, 3*128))
+	assert.True(t, errors.Is(Join(&buf, shards, 3*128+1), ErrShortData))
+}
@@ -0,0 +1,369 @@
+package leopard
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Every byte offset within the shares of a codeword (every pair of offsets j
+// and 32+j of a 64 byte block in GF(2^16)) forms a codeword on its own, so
+// leopard can process the shares in column stripes of multiples of 64 bytes
+// and produce the same result as for the shares as a whole.
+
+// stripeSize is the number of bytes of all shares of a codeword together
+// processed per column stripe.
+const stripeSize = 1 << 20
+
+// maxStripesPerGoroutine bounds the number of column stripes per goroutine
+// with WithGoroutines:
+// every stripe is a call into leopard running its FFTs over all shares, so
+// stripes narrower than a fraction of a goroutine's part of the shares cost
+// more than the smaller buffers save.
+const maxStripesPerGoroutine = 4
+
+// stripeWidth returns the width of the column stripes of the Codec's shares,
+// a multiple of 64 bytes.
+func (c *Codec) stripeWidth() int {
+	width := stripeSize / (c.origCount + c.recoveryCount) / 64 * 64
+	g := c.opts.goroutines
+	if g < 1 {
+		g = 1
+	}
+	if g > 1 {
+		perGoroutine := (c.bufferBytes/g + 63) / 64 * 64
+		if perGoroutine < width {
+			// at least one stripe per goroutine:
+			width = perGoroutine
+		}
+		// large codewords would get stripes of a few blocks otherwise. A
+		// single goroutine keeps them, so that EncodeContext and
+		// DecodeContext check ctx often:
+		if minWidth := (perGoroutine/maxStripesPerGoroutine + 63) / 64 * 64; width < minWidth {
+			width = minWidth
+		}
+	}
+	if width < 64 {
+		width = 64
+	}
+	if width > c.bufferBytes {
+		width = c.bufferBytes
+	}
+	return width
+}
+
+// EncodeContext computes the recovery shares for data like Encode, but
+// processes the shares in column stripes and checks ctx between them.
+// If ctx is done before all stripes are encoded, ctx.Err() is returned.
+func (c *Codec) EncodeContext(ctx context.Context, data [][]byte) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrCodecClosed
+	}
+	if err := c.checkShares(OpEncode, data, c.origCount, 0, false); err != nil {
+		return nil, err
+	}
+	parity := allocShares(c.recoveryCount, c.shardSize)
+	out := parity
+	if c.layout.padded() {
+		out = allocShares(c.recoveryCount, c.bufferBytes)
+	}
+	if err := c.encodeStripes(ctx, c.buffers(data), out); err != nil {
+		return nil, err
+	}
+	if c.layout.padded() {
+		for i := range parity {
+			c.layout.trim(parity[i], out[i])
+		}
+	}
+	return parity, nil
+}
+
+// DecodeContext recovers missing original and recovery shares like Decode,
+// but processes the shares in column stripes and checks ctx between them.
+// If ctx is done before all stripes are decoded, ctx.Err() is returned.
+func (c *Codec) DecodeContext(ctx context.Context, orig, recovery [][]byte) ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrCodecClosed
+	}
+	if err := c.checkShares(OpDecode, orig, c.origCount, 0, true); err != nil {
+		return nil, err
+	}
+	if err := c.checkShares(OpDecode, recovery, c.recoveryCount, c.origCount, true); err != nil {
+		return nil, err
+	}
+	avail := availabilityOf(orig, recovery)
+	total := c.origCount + c.recoveryCount
+	decoded := make([][]byte, 0, total)
+	decoded = append(decoded, orig...)
+	decoded = append(decoded, recovery...)
+	if avail.Count() == total {
+		return decoded, nil
+	}
+	if err := c.checkRecoverable(avail); err != nil {
+		return nil, err
+	}
+
+	out := make([][]byte, total)
+	for i := range out {
+		if !avail.Has(i) {
+			out[i] = make([]byte, c.bufferBytes)
+		}
+	}
+	if err := c.decodeStripes(ctx, c.buffers(decoded), avail, out, true); err != nil {
+		return nil, err
+	}
+	for i := range decoded {
+		if avail.Has(i) {
+			continue
+		}
+		if c.layout.padded() {
+			decoded[i] = make([]byte, c.shardSize)
+			c.layout.trim(decoded[i], out[i])
+		} else {
+			decoded[i] = out[i]
+		}
+	}
+	return decoded, nil
+}
+
+// encodeStripes encodes the bufferBytes long shares in stripe by stripe and
+// writes the recovery shares to out.
+func (c *Codec) encodeStripes(ctx context.Context, in, out [][]byte) error {
+	return c.forStripes(ctx, func(b backend, cols [][]byte, off, width int) error {
+		encoded, res := b.encode(columns(cols[:c.origCount], in, off, width))
+		if err := c.resultToErr(OpEncode, res); err != nil {
+			return err
+		}
+		for i := range out {
+			copy(out[i][off:off+width], encoded[i])
+		}
+		return nil
+	})
+}
+
+// decodeStripes recovers the bufferBytes long original shares missing
+// according to avail, which covers the (orig || recovery) shares in, stripe
+// by stripe and writes them to the matching entries of out.
+// If withRecovery is set, missing recovery shares are re-computed as well.
+func (c *Codec) decodeStripes(ctx context.Context, in [][]byte, avail *Availability, out [][]byte, withRecovery bool) error {
+	total := c.origCount + c.recoveryCount
+	missingOrig := avail.countMissingIn(0, c.origCount)
+	missingRecovery := 0
+	if withRecovery {
+		missingRecovery = avail.countMissingIn(c.origCount, total)
+	}
+	// the (recovered) original shares to re-compute missing recovery shares:
+	full := make([][]byte, c.origCount)
+	for i := range full {
+		full[i] = in[i]
+		if !avail.Has(i) {
+			full[i] = out[i]
+		}
+	}
+
+	return c.forStripes(ctx, func(b backend, cols [][]byte, off, width int) error {
+		if missingOrig > 0 {
+			columns(cols, in, off, width)
+			decoded, res := b.decode(cols[:c.origCount], cols[c.origCount:], avail)
+			if err := c.resultToErr(OpDecode, res); err != nil {
+				return err
+			}
+			for i := 0; i < c.origCount; i++ {
+				if !avail.Has(i) {
+					copy(out[i][off:off+width], decoded[i])
+				}
+			}
+		}
+		if missingRecovery > 0 {
+			// see decodeOriginals:
+			encoded, res := b.encode(columns(cols[:c.origCount], full, off, width))
+			if err := c.resultToErr(OpEncode, res); err != nil {
+				return err
+			}
+			for i := 0; i < c.recoveryCount; i++ {
+				if !avail.Has(c.origCount + i) {
+					copy(out[c.origCount+i][off:off+width], encoded[i])
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// decodeOriginalStripes is decodeOriginals spread across several goroutines.
+func (c *Codec) decodeOriginalStripes(orig, recovery [][]byte, avail *Availability, out [][]byte) error {
+	in := make([][]byte, 0, c.origCount+c.recoveryCount)
+	for _, shares := range [][][]byte{orig, recovery} {
+		for _, s := range shares {
+			if !avail.Has(len(in)) {
+				s = nil
+			}
+			in = append(in, s)
+		}
+	}
+	decoded := make([][]byte, len(in))
+	for i := range orig {
+		if !avail.Has(i) {
+			decoded[i] = make([]byte, c.bufferBytes)
+		}
+	}
+	if err := c.decodeStripes(context.Background(), c.buffers(in), avail, decoded, false); err != nil {
+		return err
+	}
+	for i := range orig {
+		if !avail.Has(i) {
+			c.layout.trim(out[i], decoded[i])
+		}
+	}
+	return nil
+}
+
+// forStripes calls fn for every column stripe [off, off+width) of the
+// bufferBytes long shares, checking ctx before every stripe.
+// The stripes are spread across up to WithGoroutines goroutines, each of
+// which passes its own backend and (orig || recovery) long scratch slice
+// cols to fn.
+func (c *Codec) forStripes(ctx context.Context, fn func(b backend, cols [][]byte, off, width int) error) error {
+	width := c.stripeWidth()
+	stripes := (c.bufferBytes + width - 1) / width
+	workers := c.opts.goroutines
+	if workers > stripes {
+		workers = stripes
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for len(c.stripes) < workers {
+		c.stripes = append(c.stripes, make(map[int]backend))
+	}
+
+	var (
+		next     int64 = -1
+		failed   int32
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { firstErr = err })
+		atomic.StoreInt32(&failed, 1)
+	}
+	run := func(worker int) {
+		cols := make([][]byte, c.origCount+c.recoveryCount)
+		for {
+			stripe := int(atomic.AddInt64(&next, 1))
+			if stripe >= stripes || atomic.LoadInt32(&failed) != 0 {
+				return
+			}
+			if err := ctx.Err(); err != nil {
+				fail(err)
+				return
+			}
+			off := stripe * width
+			w := width
+			if off+w > c.bufferBytes {
+				w = c.bufferBytes - off
+			}
+			b, err := c.stripeBackend(worker, w)
+			if err == nil {
+				err = fn(b, cols, off, w)
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for worker := 1; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			run(worker)
+		}(worker)
+	}
+	run(0)
+	wg.Wait()
+	return firstErr
+}
+
+// parallel reports whether calls are spread across several goroutines.
+func (c *Codec) parallel() bool {
+	return c.opts.goroutines > 1 && c.bufferBytes > 64
+}
+
+// stripeBackend returns the backend of the given worker for column stripes
+// of width bytes.
+func (c *Codec) stripeBackend(worker, width int) (backend, error) {
+	if worker == 0 && width == c.bufferBytes && !c.layout.padded() {
+		return c.b, nil
+	}
+	if b, ok := c.stripes[worker][width]; ok {
+		return b, nil
+	}
+	b, err := newBackend(c.origCount, c.recoveryCount, shareLayout{shardSize: width, bufferBytes: width}, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	c.stripes[worker][width] = b
+	return b, nil
+}
+
+// buffers returns the shares as bufferBytes long buffers, copying them to
+// zero padded buffers if padding is needed. Missing shares stay nil.
+func (c *Codec) buffers(shares [][]byte) [][]byte {
+	if !c.layout.padded() {
+		return shares
+	}
+	bufs := make([][]byte, len(shares))
+	for i, s := range shares {
+		if len(s) != 0 {
+			bufs[i] = make([]byte, c.bufferBytes)
+			c.layout.pad(bufs[i], s)
+		}
+	}
+	return bufs
+}
+
+// columns points cols at the column stripe [off, off+width) of shares and
+// returns it. Missing shares stay nil.
+func columns(cols, shares [][]byte, off, width int) [][]byte {
+	for i, s := range shares {
+		if len(s) == 0 {
+			cols[i] = nil
+			continue
+		}
+		cols[i] = s[off : off+width]
+	}
+	return cols
+}
+
+// EncodeContext computes len(data) recovery shares like Encode, but checks
+// ctx between column stripes of the shares; see Codec.EncodeContext.
+func EncodeContext(ctx context.Context, data [][]byte, opts ...Option) ([][]byte, error) {
+	origCount, bufferBytes, err := extractCounts(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCodec(OpEncode, int(origCount), int(origCount), int(bufferBytes), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.EncodeContext(ctx, data)
+}
+
+// DecodeContext recovers missing original and recovery shares like Decode,
+// but checks ctx between column stripes of the shares; see
+// Codec.DecodeContext.
+func DecodeContext(ctx context.Context, orig, recovery [][]byte, opts ...Option) ([][]byte, error) {
+	c, err := newCodecForShares(orig, recovery, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.DecodeContext(ctx, orig, recovery)
+}
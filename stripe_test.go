@@ -0,0 +1,191 @@
+package leopard
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeContext(t *testing.T) {
+	tcs := []struct {
+		name          string
+		origCount     int
+		recoveryCount int
+		shardSize     int
+		opts          []Option
+	}{
+		{"single stripe", 32, 32, 256, nil},
+		// 2048 shares take stripes of 512 bytes in GF(2^16):
+		{"stripes", 1024, 1024, 1600, nil},
+		{"stripes with padding", 1024, 1024, 1538, []Option{WithPadding()}},
+		{"pure Go stripes with padding", 1024, 512, 1538, []Option{WithPadding(), WithPureGo()}},
+		{"FF8 stripes with padding", 150, 50, 20001, []Option{WithPadding()}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewCodec(tc.origCount, tc.recoveryCount, tc.shardSize, tc.opts...)
+			require.NoError(t, err)
+			defer c.Close()
+
+			data := make([][]byte, tc.origCount)
+			for i := range data {
+				data[i] = make([]byte, tc.shardSize)
+				checkedRandBytes(data[i])
+			}
+			parity, err := c.EncodeContext(context.Background(), data)
+			require.NoError(t, err)
+			// must be identical to encoding the shares as a whole:
+			want, err := c.Encode(data)
+			require.NoError(t, err)
+			require.Equal(t, want, parity)
+
+			orig, recovery := deepCopy(data), deepCopy(parity)
+			for _, i := range rand.Perm(tc.origCount + tc.recoveryCount)[:tc.recoveryCount] {
+				if i < tc.origCount {
+					orig[i] = nil
+				} else {
+					recovery[i-tc.origCount] = nil
+				}
+			}
+			decoded, err := c.DecodeContext(context.Background(), orig, recovery)
+			require.NoError(t, err)
+			assert.Equal(t, data, decoded[:tc.origCount])
+			assert.Equal(t, parity, decoded[tc.origCount:])
+		})
+	}
+}
+
+func TestEncodeDecodeContextCanceled(t *testing.T) {
+	const originalCount = 128
+	const bufferBytes = 64
+
+	data := make([][]byte, originalCount)
+	for i := range data {
+		data[i] = make([]byte, bufferBytes)
+		checkedRandBytes(data[i])
+	}
+	parity, err := EncodeContext(context.Background(), data)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = EncodeContext(ctx, data)
+	assert.Equal(t, context.Canceled, err)
+
+	orig := deepCopy(data)
+	orig[0] = nil
+	_, err = DecodeContext(ctx, orig, parity)
+	assert.Equal(t, context.Canceled, err)
+
+	// nothing to do for complete codewords:
+	decoded, err := DecodeContext(ctx, data, parity)
+	require.NoError(t, err)
+	assert.Equal(t, append(deepCopy(data), parity...), decoded)
+}
+
+func TestGoroutines(t *testing.T) {
+	tcs := []struct {
+		name          string
+		origCount     int
+		recoveryCount int
+		shardSize     int
+		opts          []Option
+	}{
+		{"FF8", 64, 32, 4096, nil},
+		{"FF16", 512, 512, 1024, nil},
+		{"uneven stripes", 64, 64, 64 * 13, nil},
+		{"padding", 512, 256, 1000, []Option{WithPadding()}},
+		{"pure Go", 100, 100, 2048, []Option{WithPureGo()}},
+		{"single stripe", 32, 32, 64, nil},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			single, err := NewCodec(tc.origCount, tc.recoveryCount, tc.shardSize, tc.opts...)
+			require.NoError(t, err)
+			defer single.Close()
+			c, err := NewCodec(tc.origCount, tc.recoveryCount, tc.shardSize, append(tc.opts, WithGoroutines(4))...)
+			require.NoError(t, err)
+			defer c.Close()
+
+			data := make([][]byte, tc.origCount)
+			for i := range data {
+				data[i] = make([]byte, tc.shardSize)
+				checkedRandBytes(data[i])
+			}
+			// must be byte-identical to the single call:
+			want, err := single.Encode(data)
+			require.NoError(t, err)
+			parity, err := c.Encode(data)
+			require.NoError(t, err)
+			require.Equal(t, want, parity)
+
+			shards := append(deepCopy(data), deepCopy(parity)...)
+			for _, i := range rand.Perm(tc.origCount + tc.recoveryCount)[:tc.recoveryCount] {
+				shards[i] = nil
+			}
+			recovered, err := c.Recover(shards[:tc.origCount], shards[tc.origCount:])
+			require.NoError(t, err)
+			assert.Equal(t, append(deepCopy(data), parity...), recovered)
+
+			require.NoError(t, c.Reconstruct(shards))
+			assert.Equal(t, recovered, shards)
+		})
+	}
+}
+
+func TestStripeWidth(t *testing.T) {
+	tcs := []struct {
+		origCount, recoveryCount, bufferBytes, goroutines int
+		want                                              int
+	}{
+		// 1 MiB per stripe:
+		{1024, 1024, 1600, 0, 512},
+		{32, 32, 256, 0, 256},
+		// at least one stripe per goroutine:
+		{64, 32, 4096, 4, 1024},
+		// large codewords don't fall back to 64 byte stripes:
+		{32768, 32768, 4096, 8, 128},
+		// but without goroutines they do, to check the context often:
+		{32768, 32768, 4096, 0, 64},
+		{32768, 32768, 64, 8, 64},
+	}
+	for _, tc := range tcs {
+		c := &Codec{
+			origCount:     tc.origCount,
+			recoveryCount: tc.recoveryCount,
+			bufferBytes:   tc.bufferBytes,
+			opts:          options{goroutines: tc.goroutines},
+		}
+		assert.Equal(t, tc.want, c.stripeWidth(), "%+v", tc)
+	}
+}
+
+func BenchmarkGoroutines(b *testing.B) {
+	const originalCount = 4096
+	const bufferBytes = 4096
+
+	data := make([][]byte, originalCount)
+	for i := range data {
+		data[i] = make([]byte, bufferBytes)
+		checkedRandBytes(data[i])
+	}
+	for _, g := range []int{1, 2, 4, 8} {
+		c, err := NewCodec(originalCount, originalCount, bufferBytes, WithGoroutines(g))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("%d goroutines", g), func(b *testing.B) {
+			b.SetBytes(originalCount * bufferBytes)
+			for n := 0; n < b.N; n++ {
+				if _, err := c.Encode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		c.Close()
+	}
+}
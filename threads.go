@@ -0,0 +1,93 @@
+package leopard
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// defaultThreads is the number of OpenMP threads set by SetThreads,
+// 0 for GOMAXPROCS divided between the running calls.
+var defaultThreads int32
+
+// activeCalls is the number of encode and decode calls currently running in
+// the C library (a batch call counts once), see startCall.
+var activeCalls int32
+
+// SetThreads bounds the number of threads the C library runs a single
+// encode or decode on if it was built with OpenMP (see
+// CapabilityReport.OpenMP), and the number of codewords EncodeBatch and
+// DecodeBatch process at once.
+// n <= 0 restores the default, which divides GOMAXPROCS between the calls
+// running at the same time: a call started while 3 others are running gets
+// a quarter of the CPUs. The share is fixed when a call starts, so the
+// CPUs can still be oversubscribed briefly while calls start and finish.
+// The bound is applied as well if it is unknown whether the C library was
+// built with OpenMP, as it has no effect on a library built without.
+// WithThreads overrides the bound for a single Codec. It has no effect on
+// the pure Go implementation, which runs on a single goroutine (see
+// WithGoroutines).
+func SetThreads(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt32(&defaultThreads, int32(n))
+}
+
+// Threads returns the number of threads the C library would run an encode
+// or decode started now on, see SetThreads. It is 1 if the C library was
+// built without OpenMP or the package without cgo.
+func Threads() int {
+	return effectiveThreads(0, runningCalls()+1)
+}
+
+// effectiveThreads returns the number of threads the C library runs a call
+// on if n were requested while calls calls are running (including it),
+// where n <= 0 requests the default.
+func effectiveThreads(n, calls int) int {
+	if openMPSupport() == Unsupported {
+		return 1
+	}
+	return requestedThreads(n, calls)
+}
+
+// libraryThreads returns the number of OpenMP threads to set for a call
+// into the C library requesting n threads while calls calls are running,
+// or 0 to leave OpenMP's default in place if the library doesn't use OpenMP.
+func libraryThreads(n, calls int) int {
+	if openMPSupport() == Unsupported {
+		return 0
+	}
+	return requestedThreads(n, calls)
+}
+
+// requestedThreads returns the number of threads requested by n while calls
+// calls are running (including the one asking), see SetThreads.
+func requestedThreads(n, calls int) int {
+	if n <= 0 {
+		n = int(atomic.LoadInt32(&defaultThreads))
+	}
+	if n <= 0 {
+		if calls < 1 {
+			calls = 1
+		}
+		n = runtime.GOMAXPROCS(0) / calls
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// startCall registers a call into the C library and returns the number of
+// calls running including it; endCall must be called once it returns.
+func startCall() int {
+	return int(atomic.AddInt32(&activeCalls, 1))
+}
+
+func endCall() {
+	atomic.AddInt32(&activeCalls, -1)
+}
+
+func runningCalls() int {
+	return int(atomic.LoadInt32(&activeCalls))
+}
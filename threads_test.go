@@ -0,0 +1,80 @@
+package leopard
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreads(t *testing.T) {
+	defer SetThreads(0)
+	switch Capabilities().OpenMP {
+	case Unsupported:
+		SetThreads(4)
+		assert.Equal(t, 1, Threads())
+		return
+	}
+	// if it is unknown whether the C library uses OpenMP, the threads are
+	// bounded all the same:
+	assert.Equal(t, runtime.GOMAXPROCS(0), Threads())
+	SetThreads(3)
+	assert.Equal(t, 3, Threads())
+	assert.Equal(t, 3, Capabilities().Threads)
+
+	c, err := NewCodec(4, 4, 64)
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, 3, c.Threads())
+	SetThreads(0)
+	assert.Equal(t, runtime.GOMAXPROCS(0), c.Threads())
+
+	c2, err := NewCodec(4, 4, 64, WithThreads(2))
+	require.NoError(t, err)
+	defer c2.Close()
+	assert.Equal(t, 2, c2.Threads())
+
+	c3, err := NewCodec(4, 4, 64, WithThreads(2), WithPureGo())
+	require.NoError(t, err)
+	defer c3.Close()
+	assert.Equal(t, 1, c3.Threads())
+}
+
+func TestThreadsDividedBetweenCalls(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+	assert.Equal(t, 8, requestedThreads(0, 1))
+	assert.Equal(t, 2, requestedThreads(0, 3))
+	assert.Equal(t, 1, requestedThreads(0, 16))
+	// an explicit bound isn't divided:
+	assert.Equal(t, 5, requestedThreads(5, 3))
+	SetThreads(3)
+	defer SetThreads(0)
+	assert.Equal(t, 3, requestedThreads(0, 4))
+}
+
+func TestThreadsResults(t *testing.T) {
+	const originalCount = 512
+	const bufferBytes = 256
+
+	data := make([][]byte, originalCount)
+	for i := range data {
+		data[i] = make([]byte, bufferBytes)
+		checkedRandBytes(data[i])
+	}
+	want, err := Encode(data, WithThreads(1))
+	require.NoError(t, err)
+	for _, threads := range []int{2, 4} {
+		parity, err := Encode(data, WithThreads(threads))
+		require.NoError(t, err)
+		assert.Equal(t, want, parity, "%d threads", threads)
+
+		orig := deepCopy(data)
+		for i := 0; i < originalCount; i += 2 {
+			orig[i] = nil
+		}
+		recovered, err := Recover(orig, parity, WithThreads(threads))
+		require.NoError(t, err)
+		assert.Equal(t, data, recovered[:originalCount])
+	}
+}
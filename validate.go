@@ -0,0 +1,153 @@
+package leopard
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// The checks below run before any data is handed to leopard: leopard reads
+// buffer_bytes from every buffer it is passed and can't detect any misuse
+// itself, so malformed input (e.g. received from the network) has to be
+// rejected here rather than resulting in undefined behaviour in C.
+
+// checkCounts validates a (original, recovery) pair against Leopard's limits.
+// It reports failures of op as a *LeopardError, which unwraps to
+// ErrInvalidCounts or ErrTooMuchData.
+func checkCounts(op string, origCount, recoveryCount int) error {
+	if recoveryCount < 1 || recoveryCount > origCount {
+		return countsError(op, LeopardInvalidcounts, origCount, recoveryCount,
+			"recovery count must be in [1, %d]", origCount)
+	}
+	if origCount+recoveryCount > MaxShards {
+		return countsError(op, LeopardToomuchdata, origCount, recoveryCount,
+			"more than %d shares", MaxShards)
+	}
+	// Unless one of the counts is 1, leopard works on the next power of two
+	// of recoveryCount internally, which has to fit as well:
+	if origCount > 1 && recoveryCount > 1 && origCount+nextPow2(recoveryCount) > MaxShards {
+		return countsError(op, LeopardToomuchdata, origCount, recoveryCount,
+			"more than %d shares with the recovery count rounded up to %d", MaxShards, nextPow2(recoveryCount))
+	}
+	return nil
+}
+
+// ValidateShardSize returns ErrInvalidSize unless NewCodec accepts shares of
+// shardSize bytes with opts for any codeword shape, without allocating
+// anything: shardSize must be a positive multiple of 64 or, with WithPadding,
+// a positive even size.
+func ValidateShardSize(shardSize int, opts ...Option) error {
+	return checkShardSize(shardSize, true, newOptions(opts))
+}
+
+// checkShardSize validates shardSize for a codeword whose symbols are 2 bytes
+// wide if wide is set: padding works in whole symbols, which odd sizes of
+// GF(2^16) shares can't be split into.
+func checkShardSize(shardSize int, wide bool, o options) error {
+	if shardSize <= 0 || (shardSize%64 != 0 && !o.padding) {
+		return ErrInvalidSize
+	}
+	if wide && shardSize%2 != 0 {
+		return ErrInvalidSize
+	}
+	return nil
+}
+
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// checkShares verifies that shares has count entries of the Codec's shard size.
+// If allowMissing is set, empty entries are accepted as missing shares.
+// offset is the index of shares[0] within (orig || recovery).
+func (c *Codec) checkShares(op string, shares [][]byte, count, offset int, allowMissing bool) error {
+	if len(shares) != count {
+		return c.newError(op, LeopardInvalidcounts, -1, "expected %d shares, got %d", count, len(shares))
+	}
+	for i, s := range shares {
+		if len(s) == 0 && allowMissing {
+			continue
+		}
+		if len(s) != c.shardSize {
+			return c.newError(op, LeopardInvalidinput, offset+i, "%d bytes, expected %d", len(s), c.shardSize)
+		}
+	}
+	return nil
+}
+
+// checkAvailable verifies that shards is a full codeword described by avail,
+// with all present shares being of the Codec's shard size.
+func (c *Codec) checkAvailable(shards [][]byte, avail *Availability) error {
+	total := c.origCount + c.recoveryCount
+	if len(shards) != total || avail.Len() != total {
+		return c.newError(OpDecode, LeopardInvalidcounts, -1,
+			"expected %d shares, got %d shares and an availability of %d", total, len(shards), avail.Len())
+	}
+	for i, s := range shards {
+		if avail.Has(i) && len(s) != c.shardSize {
+			return c.newError(OpDecode, LeopardInvalidinput, i,
+				"present share has %d bytes, expected %d", len(s), c.shardSize)
+		}
+	}
+	return nil
+}
+
+// checkRecoverable verifies that enough shares are present to recover the
+// missing ones: any OrigCount shares of a codeword suffice.
+func (c *Codec) checkRecoverable(avail *Availability) error {
+	if present := avail.Count(); present < c.origCount {
+		return c.newError(OpDecode, LeopardNeedmoredata, -1,
+			"%d shares present, at least %d needed", present, c.origCount)
+	}
+	return nil
+}
+
+// checkAliasing verifies that none of the shares written to (writes) overlaps
+// in memory with another written share or any share read from (reads).
+// Read-only shares may alias each other (e.g. a shared all-zero padding share)
+// as they are only ever copied.
+// The index of an offending share is reported relative to (orig || recovery)
+// via writeIndex.
+func (c *Codec) checkAliasing(op string, writes [][]byte, writeIndex func(int) int, reads ...[][]byte) error {
+	ws := spansOf(writes)
+	if len(ws) == 0 {
+		return nil
+	}
+	sort.Slice(ws, func(i, j int) bool { return ws[i].start < ws[j].start })
+	for i := 1; i < len(ws); i++ {
+		if ws[i].start < ws[i-1].end {
+			return c.newError(op, LeopardInvalidinput, writeIndex(ws[i].idx), "aliases another output share")
+		}
+	}
+	for _, r := range reads {
+		for _, rs := range spansOf(r) {
+			// the last written span starting before rs ends is the only
+			// candidate for an overlap as the written spans are disjoint:
+			j := sort.Search(len(ws), func(i int) bool { return ws[i].start >= rs.end }) - 1
+			if j >= 0 && ws[j].end > rs.start {
+				return c.newError(op, LeopardInvalidinput, writeIndex(ws[j].idx), "aliases an input share")
+			}
+		}
+	}
+	return nil
+}
+
+type span struct {
+	start, end uintptr
+	idx        int
+}
+
+func spansOf(shares [][]byte) []span {
+	spans := make([]span, 0, len(shares))
+	for i, s := range shares {
+		if len(s) == 0 {
+			continue
+		}
+		start := uintptr(unsafe.Pointer(&s[0]))
+		spans = append(spans, span{start: start, end: start + uintptr(len(s)), idx: i})
+	}
+	return spans
+}
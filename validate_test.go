@@ -0,0 +1,100 @@
+package leopard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCounts(t *testing.T) {
+	tcs := []struct {
+		name          string
+		origCount     int
+		recoveryCount int
+		want          error
+	}{
+		{"1:1", 1, 1, nil},
+		{"max 1:1", 32768, 32768, nil},
+		{"no recovery", 4, 0, ErrInvalidCounts},
+		{"more recovery than orig", 4, 5, ErrInvalidCounts},
+		{"too many shares", 40000, 30000, ErrTooMuchData},
+		// 40000 + nextPow2(20000) = 72768:
+		{"too many shares after rounding", 40000, 20000, ErrTooMuchData},
+		{"single recovery is not rounded", 65535, 1, nil},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkCounts(OpEncode, tc.origCount, tc.recoveryCount)
+			if tc.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tc.want), err)
+			var leoErr *LeopardError
+			require.True(t, errors.As(err, &leoErr))
+			assert.Equal(t, OpEncode, leoErr.Op)
+			assert.Equal(t, tc.origCount, leoErr.OrigCount)
+			assert.Equal(t, tc.recoveryCount, leoErr.RecoveryCount)
+		})
+	}
+}
+
+func TestValidateMismatchedLengths(t *testing.T) {
+	orig := [][]byte{make([]byte, 64), nil, make([]byte, 128), make([]byte, 64)}
+	recovery := [][]byte{make([]byte, 64), make([]byte, 64)}
+	_, err := Recover(orig, recovery)
+	var leoErr *LeopardError
+	require.True(t, errors.As(err, &leoErr))
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+	assert.Equal(t, 2, leoErr.Shard)
+
+	// zero length data shares can't be encoded:
+	orig[1] = []byte{}
+	orig[2] = orig[2][:64]
+	_, err = EncodeWithRecovery(orig, 2)
+	require.True(t, errors.As(err, &leoErr))
+	assert.Equal(t, 1, leoErr.Shard)
+}
+
+func TestValidateTooFewShares(t *testing.T) {
+	c, err := NewCodec(4, 2, 64)
+	require.NoError(t, err)
+	defer c.Close()
+
+	shards := [][]byte{nil, make([]byte, 64), nil, make([]byte, 64), nil, make([]byte, 64)}
+	err = c.Reconstruct(shards)
+	assert.True(t, errors.Is(err, ErrNeedMoreData))
+	assert.Contains(t, err.Error(), "3 shares present, at least 4 needed")
+}
+
+func TestValidateAliasing(t *testing.T) {
+	c, err := NewCodec(4, 2, 64)
+	require.NoError(t, err)
+	defer c.Close()
+
+	// read-only shares may alias each other:
+	zero := make([]byte, 64)
+	data := [][]byte{zero, zero, zero, make([]byte, 64)}
+	parity := [][]byte{make([]byte, 64), make([]byte, 64)}
+	require.NoError(t, c.EncodeInto(data, parity))
+
+	// but outputs must not alias inputs ...
+	var leoErr *LeopardError
+	err = c.EncodeInto(data, [][]byte{parity[0], data[3]})
+	require.True(t, errors.As(err, &leoErr))
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+	assert.Equal(t, 5, leoErr.Shard)
+
+	// ... nor each other, even if they only partially overlap:
+	buf := make([]byte, 64*7)
+	out := [][]byte{buf[0:64], buf[64:128], buf[128:192], buf[192:256], buf[256:320], buf[300:364]}
+	err = c.RecoverInto(append(data[:3:3], nil), parity, out)
+	require.True(t, errors.As(err, &leoErr))
+	assert.Equal(t, 5, leoErr.Shard)
+
+	out[5] = buf[320:384]
+	require.NoError(t, c.RecoverInto(append(data[:3:3], nil), parity, out))
+	assert.Equal(t, data[3], out[3])
+}
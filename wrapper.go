@@ -1,189 +1,257 @@
 package leopard
 
-//#include <stdlib.h>
-import "C"
 import (
 	"errors"
-	"fmt"
-	"unsafe"
-
-	// to avoid repeatedly writing leopard.Leopard* below:
-	. "github.com/celestiaorg/go-leopard/leopard"
-)
-
-var (
-	ErrNeedMoreData  = errors.New("not enough recovery data received")
-	ErrTooMuchData   = errors.New("buffer counts are too high")
-	ErrInvalidSize   = errors.New("buffer size must be a multiple of 64 bytes")
-	ErrInvalidCounts = errors.New("invalid counts provided")
-	ErrInvalidInput  = errors.New("a function parameter was invalid")
-	ErrPlatform      = errors.New("platform is unsupported")
-
-	ErrCallInitialize = errors.New("call Init() first")
-
-	errAllBuffersEmpty = errors.New("all buffers are empty")
+	"sync"
 )
 
 const version = 2
 
-func init() {
-	if err := Init(); err != nil {
-		panic(fmt.Sprintf("Unexpected error while initializing leopard %v", err))
-	}
-}
+// MaxShards is the maximum number of original plus recovery shares
+// Leopard can handle in a single codeword.
+const MaxShards = 65536
 
-func leopardResultToErr(errCode Leopardresult) error {
-	switch errCode {
-	case LeopardSuccess:
-		return nil
-	case LeopardNeedmoredata:
-		return ErrNeedMoreData
-	case LeopardToomuchdata:
-		return ErrTooMuchData
-	case LeopardInvalidsize:
-		return ErrInvalidSize
-	case LeopardInvalidcounts:
-		return ErrInvalidCounts
-	case LeopardInvalidinput:
-		return ErrInvalidInput
-	case LeopardPlatform:
-		return ErrPlatform
-	case LeopardCallinitialize:
-		return ErrCallInitialize
-	default:
-		panic("unexpected Leopard return code")
-	}
-}
+var (
+	initOnce sync.Once
+	initErr  error
+)
 
+// Init initializes leopard and reports whether the platform is supported.
+// Leopard is initialized lazily on first use, so calling Init is optional;
+// call it explicitly to handle an unsupported platform up front.
+// Only the first call initializes, subsequent calls return the same result.
+// Without cgo, the pure Go implementation needs no initialization and Init
+// always succeeds.
 func Init() error {
-	return leopardResultToErr(Leopardresult(LeoInit(version)))
+	initOnce.Do(func() {
+		initErr = initLibrary()
+	})
+	return initErr
 }
 
 // Encode takes a slice of equally sized byte slices and computes len(data) parity shares.
 // This means you can lose half of (data || encodeWork) and still recover the data.
-func Encode(data [][]byte) (encodeWork [][]byte, err error) {
+func Encode(data [][]byte, opts ...Option) (encodeWork [][]byte, err error) {
+	return EncodeWithRecovery(data, len(data), opts...)
+}
+
+// EncodeWithRecovery takes a slice of equally sized byte slices and computes
+// recoveryCount parity shares. recoveryCount must be in [1, len(data)] and
+// len(data)+recoveryCount must not exceed MaxShards. Unless one of the counts
+// is 1, leopard rounds recoveryCount up to the next power of two internally, so
+// len(data) plus that power of two must not exceed MaxShards either
+// (e.g. 40000+20000 is rejected as 40000+32768 is too large).
+// Any recoveryCount shares of (data || encodeWork) can be lost and the data
+// can still be recovered.
+func EncodeWithRecovery(data [][]byte, recoveryCount int, opts ...Option) (encodeWork [][]byte, err error) {
 	origCount, bufferBytes, err := extractCounts(data)
 	if err != nil {
 		return nil, err
 	}
-	recoveryCount := origCount
-	workCount := LeoEncodeWorkCount(origCount, recoveryCount)
-	origDataPtrs := copyToCmallocedPtrs(data)
-	defer freeAll(origDataPtrs)
+	c, err := newCodec(OpEncode, int(origCount), recoveryCount, int(bufferBytes), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.Encode(data)
+}
 
-	encodeWork = make([][]byte, workCount)
-	for i := uint(0); i < uint(workCount); i++ {
-		encodeWork[i] = make([]byte, bufferBytes)
+// EncodeInto takes a slice of equally sized byte slices and computes len(parity)
+// parity shares like EncodeWithRecovery, but writes them into the passed in
+// parity slices, which must have the same size as the data shares.
+func EncodeInto(data, parity [][]byte, opts ...Option) error {
+	origCount, bufferBytes, err := extractCounts(data)
+	if err != nil {
+		return err
+	}
+	c, err := newCodec(OpEncode, int(origCount), len(parity), int(bufferBytes), opts)
+	if err != nil {
+		return err
 	}
-	encodeWorkPtr := copyToCmallocedPtrs(encodeWork)
-	defer freeAll(encodeWorkPtr)
+	defer c.Close()
+	return c.EncodeInto(data, parity)
+}
 
-	err = leopardResultToErr(LeoEncode(
-		bufferBytes,
-		origCount,
-		recoveryCount,
-		workCount,
-		origDataPtrs,
-		encodeWorkPtr))
+// Verify reports whether parity holds the len(parity) recovery shares
+// EncodeWithRecovery computes for data.
+func Verify(data, parity [][]byte, opts ...Option) (bool, error) {
+	mismatches, err := ParityMismatches(data, parity, opts...)
 	if err != nil {
-		return
+		return false, err
 	}
-	toGoByte(encodeWorkPtr, encodeWork, int(bufferBytes))
-	// XXX: We only return half the data here as the other half is all zeroes
-	// and superfluous.
-	// For details see: https://github.com/catid/leopard/issues/15#issuecomment-631391392
-	return encodeWork[:origCount], nil
+	return len(mismatches) == 0, nil
 }
 
-// Recover takes in what is left from the original data and the extended recovery data
-// and recovers missing original data (upto half of the total data can be missing).
-// Missing data (either original or recovery) has to be nil when passed in.
-func Recover(orig, recovery [][]byte) (decodeWork [][]byte, err error) {
-	if len(orig) != len(recovery) {
-		err = fmt.Errorf(
-			"recovery is only implemented for len(orig)==len(recovery), got: %v != %v",
-			len(orig),
-			len(recovery),
-		)
-		return
+// ParityMismatches re-encodes data and returns the indices of the shares in
+// parity which differ from the computed recovery shares.
+func ParityMismatches(data, parity [][]byte, opts ...Option) ([]int, error) {
+	origCount, bufferBytes, err := extractCounts(data)
+	if err != nil {
+		return nil, err
 	}
-	_, bufferBytesRecov, _ := extractCounts(recovery)
-	_, bufferBytesOrig, _ := extractCounts(orig)
-	bufferBytes := max(bufferBytesRecov, bufferBytesOrig)
-	if bufferBytes == 0 {
-		err = errAllBuffersEmpty
-		return
+	c, err := newCodec(OpEncode, int(origCount), len(parity), int(bufferBytes), opts)
+	if err != nil {
+		return nil, err
 	}
-	origCount := uint32(len(orig))
-	recoveryCount := origCount
-	decodeWorkCount := LeoDecodeWorkCount(origCount, recoveryCount)
+	defer c.Close()
+	return c.ParityMismatches(data, parity)
+}
 
-	decodeWork = make([][]byte, decodeWorkCount)
-	for i := uint(0); i < uint(decodeWorkCount); i++ {
-		decodeWork[i] = make([]byte, bufferBytes)
+// Recover takes in what is left from the original data and the extended recovery data
+// and recovers missing original and missing recovery data.
+// Any len(recovery) shares of (orig || recovery) can be missing.
+// On success, it returns a copy of (orig || recovery) with all data recovered.
+// Missing data (either original or recovery) has to be nil when passed in.
+func Recover(orig, recovery [][]byte, opts ...Option) (decodeWork [][]byte, err error) {
+	c, err := newCodecForShares(orig, recovery, opts)
+	if err != nil {
+		return nil, err
 	}
-	decodeWorkPtr := copyToCmallocedPtrs(decodeWork)
-	defer freeAll(decodeWorkPtr)
-	origDataPtr := copyToCmallocedPtrs(orig)
-	defer freeAll(origDataPtr)
-
-	recoveryDataPtr := copyToCmallocedPtrs(recovery)
-	defer freeAll(recoveryDataPtr)
+	defer c.Close()
+	return c.Recover(orig, recovery)
+}
 
-	err = leopardResultToErr(LeoDecode(
-		bufferBytes,
-		origCount,
-		recoveryCount,
-		decodeWorkCount,
-		origDataPtr,
-		recoveryDataPtr,
-		decodeWorkPtr))
+// RecoverInto recovers missing original and recovery data like Recover,
+// but writes (orig || recovery) into out, which must consist of
+// len(orig)+len(recovery) slices of the share size.
+func RecoverInto(orig, recovery, out [][]byte, opts ...Option) error {
+	c, err := newCodecForShares(orig, recovery, opts)
 	if err != nil {
-		return
+		return err
 	}
-
-	toGoByte(decodeWorkPtr, decodeWork, int(bufferBytes))
-	// leopard only recovers missing original chunks:
-	// decodeWork = decodeWork[:len(decodeWork)/2]
-	// modified fork at:
-	// https://github.com/Liamsi/leopard/tree/recover_parity_chunks_too
-	return
+	defer c.Close()
+	return c.RecoverInto(orig, recovery, out)
 }
 
 // Decode takes in what is left from the original data and the extended recovery data
 // and recovers missing original or missing recovery data
-// (upto half of the total data can be missing).
-// Note the only difference to Recover is that Decode also recovers missing parity shares.
+// (any len(recovery) shares can be missing).
+// Note the only difference to Recover is that Decode returns the passed in
+// shares as is instead of copying them.
 // On success, it returns (orig || recovery) with all data recovered.
 // Missing data (either original or recovery) has to be nil when passed in.
-func Decode(orig, recovery [][]byte) (decoded [][]byte, err error) {
-	decoded, err = Recover(orig, recovery)
+func Decode(orig, recovery [][]byte, opts ...Option) (decoded [][]byte, err error) {
+	c, err := newCodecForShares(orig, recovery, opts)
 	if err != nil {
 		return nil, err
 	}
-	for i := 0; i < len(orig); i++ {
-		if orig[i] != nil {
-			decoded[i] = orig[i]
-		}
+	defer c.Close()
+	return c.Decode(orig, recovery)
+}
+
+// Reconstruct takes (orig || recovery) of which the first origCount shares
+// are the original data and recovers the missing shares in place.
+// Missing shares have to be nil when passed in; only these entries of shards
+// are replaced, all present shares are left untouched.
+func Reconstruct(shards [][]byte, origCount int, opts ...Option) error {
+	if origCount < 0 || origCount > len(shards) {
+		return countsError(OpDecode, LeopardInvalidcounts, origCount, len(shards)-origCount,
+			"original count must be in [0, %d]", len(shards))
 	}
-	for i := 0; i < len(recovery); i++ {
-		if recovery[i] != nil {
-			offset := 0
-			// Special case: 1 share. In this case, Recover returns only one share; due to
-			// - https://github.com/catid/leopard/blob/22ddc7804998d31c8f1a2617ee720e063b1fa6cd/leopard.cpp#L207-L208 and
-			// - https://github.com/catid/leopard/blob/22ddc7804998d31c8f1a2617ee720e063b1fa6cd/leopard.cpp#L278-L283
-			// For all other cases Recover returns 2*len(orig) that len(orig) is a power of 2 (hence the offset applies
-			// below).
-			if len(orig) > 1 {
-				offset = len(orig)
-			}
-			decoded[i+offset] = recovery[i]
+	c, err := newCodecForShares(shards[:origCount], shards[origCount:], opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Reconstruct(shards)
+}
+
+// ReconstructData is like Reconstruct, but only recovers the missing original
+// shares; missing recovery shares are left nil.
+func ReconstructData(shards [][]byte, origCount int, opts ...Option) error {
+	if origCount < 0 || origCount > len(shards) {
+		return countsError(OpDecode, LeopardInvalidcounts, origCount, len(shards)-origCount,
+			"original count must be in [0, %d]", len(shards))
+	}
+	c, err := newCodecForShares(shards[:origCount], shards[origCount:], opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.ReconstructData(shards)
+}
+
+// ReconstructAvailable is like Reconstruct, but takes the shares which are
+// present from avail instead of treating nil entries as missing.
+// Present shares which are malformed (e.g. of the wrong size) are rejected
+// instead of being treated as missing; see Codec.ReconstructAvailable.
+// A nil avail treats the non-empty shares as present, like Reconstruct.
+func ReconstructAvailable(shards [][]byte, origCount int, avail *Availability, opts ...Option) error {
+	if origCount < 0 || origCount > len(shards) {
+		return countsError(OpDecode, LeopardInvalidcounts, origCount, len(shards)-origCount,
+			"original count must be in [0, %d]", len(shards))
+	}
+	if avail == nil {
+		avail = availabilityOf(shards)
+	}
+	shardSize := presentShardSize(shards, avail)
+	if shardSize == 0 {
+		return errAllBuffersEmpty
+	}
+	c, err := newCodec(OpDecode, origCount, len(shards)-origCount, shardSize, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.ReconstructAvailable(shards, avail)
+}
+
+// presentShardSize returns the most common length of the non-empty shares
+// present according to avail, or 0 if there is none.
+// Taking the majority rather than the first present share means that a single
+// malformed share is reported as such by Codec.ReconstructAvailable instead of
+// all the correct ones disagreeing with it.
+func presentShardSize(shards [][]byte, avail *Availability) int {
+	counts := make(map[int]int)
+	shardSize := 0
+	for i := 0; i < avail.Len() && i < len(shards); i++ {
+		size := len(shards[i])
+		if !avail.Has(i) || size == 0 {
+			continue
 		}
+		counts[size]++
+		// ties go to the size seen first:
+		if counts[size] > counts[shardSize] {
+			shardSize = size
+		}
+	}
+	return shardSize
+}
+
+// newCodecForShares returns a Codec matching the shape of the passed in
+// (partially missing) original and recovery shares.
+func newCodecForShares(orig, recovery [][]byte, opts []Option) (*Codec, error) {
+	if err := checkCounts(OpDecode, len(orig), len(recovery)); err != nil {
+		return nil, err
+	}
+	_, bufferBytesRecov, _ := extractCounts(recovery)
+	_, bufferBytesOrig, _ := extractCounts(orig)
+	bufferBytes := max(bufferBytesRecov, bufferBytesOrig)
+	if bufferBytes == 0 {
+		return nil, errAllBuffersEmpty
 	}
+	return newCodec(OpDecode, len(orig), len(recovery), int(bufferBytes), opts)
+}
 
+// countMissing returns the number of missing (empty) shares.
+func countMissing(shares [][]byte) (missing int) {
+	for _, s := range shares {
+		if len(s) == 0 {
+			missing++
+		}
+	}
 	return
 }
 
+// copyPresent copies all non-empty shares of src into dst.
+func copyPresent(dst, src [][]byte) {
+	for i, s := range src {
+		if len(s) != 0 {
+			copy(dst[i], s)
+		}
+	}
+}
+
 func max(x uint64, y uint64) uint64 {
 	if x < y {
 		return y
@@ -200,57 +268,9 @@ func extractCounts(data [][]byte) (dataLen uint32, bufferBytes uint64, err error
 	for _, d := range data {
 		if len(d) != 0 {
 			bufferBytes = uint64(len(d))
-			if bufferBytes%64 != 0 {
-				err = ErrInvalidSize
-			}
 			return
 		}
 	}
 	err = errAllBuffersEmpty
 	return
 }
-
-// copy over to C allocated memory:
-func copyToCmallocedPtrs(data [][]byte) []unsafe.Pointer {
-	res := make([]unsafe.Pointer, len(data))
-	for i, d := range data {
-		res[i] = copyByteBuffer(d)
-	}
-	return res
-}
-
-func copyByteBuffer(d []byte) unsafe.Pointer {
-	if len(d) > 0 {
-		return C.CBytes(d)
-	} else {
-		// keep this nil as Leopard uses this internally
-		return nil
-	}
-}
-
-func toGoByte(ps []unsafe.Pointer, res [][]byte, bufferBytes int) {
-	if len(ps) != len(res) {
-		panic("can't convert back to go slice")
-	}
-	for i, p := range ps {
-		res[i] = make([]byte, bufferBytes)
-		b := res[i]
-		for j := 0; j < bufferBytes; j++ {
-			// creates a copy of the data
-			b[j] = *(*byte)(unsafe.Pointer(uintptr(p) + uintptr(j)))
-		}
-	}
-}
-
-// wrapper around C.freeAll (can also be used in tests)
-func freeAndNil(p unsafe.Pointer) {
-	if p != nil {
-		C.free(p)
-	}
-}
-
-func freeAll(ps []unsafe.Pointer) {
-	for _, p := range ps {
-		freeAndNil(p)
-	}
-}
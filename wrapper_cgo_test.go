@@ -0,0 +1,73 @@
+//go:build cgo
+// +build cgo
+
+package leopard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemNoLeaks(t *testing.T) {
+	const originalCount = 64
+	const bufferBytes = 128
+
+	SetMemDebug(true)
+	defer SetMemDebug(false)
+	before := MemStats()
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := Encode(originalData)
+	require.NoError(t, err)
+	originalData[0], encoded[1] = nil, nil
+	_, err = Recover(originalData, encoded)
+	require.NoError(t, err)
+	_, err = EncodeBatch([][][]byte{encoded[2:], encoded[2:]}, WithPadding())
+	require.NoError(t, err)
+	assert.Equal(t, before.Allocs, MemStats().Allocs)
+	assert.Equal(t, before.Bytes, MemStats().Bytes)
+	assert.Greater(t, MemStats().TotalAllocs, before.TotalAllocs)
+
+	// a Codec holds its buffers until it is closed, which the debug mode
+	// tracks down to the allocating call:
+	c, err := NewCodec(originalCount, originalCount, bufferBytes)
+	require.NoError(t, err)
+	stats := MemStats()
+	assert.Greater(t, stats.Allocs, before.Allocs)
+	require.Len(t, stats.Live, stats.Allocs-before.Allocs)
+	assert.Equal(t, bufferBytes, stats.Live[0].Bytes)
+	assert.Contains(t, stats.Live[0].Stack, "NewCodec")
+	require.NoError(t, c.Close())
+
+	// padded batch shares are copied to buffers the Codec reuses:
+	c, err = NewCodec(originalCount, originalCount, bufferBytes-28, WithPadding())
+	require.NoError(t, err)
+	batch := make([][][]byte, 2)
+	for i := range batch {
+		for _, s := range originalData[1:] {
+			batch[i] = append(batch[i], s[:bufferBytes-28])
+		}
+		batch[i] = append(batch[i], make([]byte, bufferBytes-28))
+	}
+	_, err = c.EncodeBatch(batch)
+	require.NoError(t, err)
+	stats = MemStats()
+	_, err = c.EncodeBatch(batch)
+	require.NoError(t, err)
+	assert.Equal(t, stats.TotalAllocs, MemStats().TotalAllocs)
+	require.NoError(t, c.Close())
+
+	buf, err := NewShardBuffer(4, bufferBytes)
+	require.NoError(t, err)
+	assert.Equal(t, before.Bytes+4*bufferBytes, MemStats().Bytes)
+	buf.Free()
+
+	assert.Equal(t, before.Allocs, MemStats().Allocs)
+	assert.Empty(t, MemStats().Live)
+}
@@ -3,14 +3,14 @@ package leopard
 import (
 	"bytes"
 	"crypto/md5"
+	"errors"
 	"math/rand"
 	"testing"
-	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/celestiaorg/go-leopard/leopard"
+	"github.com/celestiaorg/go-leopard/internal/goleo"
 )
 
 func TestInitLeo(t *testing.T) {
@@ -36,6 +36,47 @@ func TestEncodeSimple(t *testing.T) {
 	assert.Equal(t, originalCount, len(encoded))
 }
 
+func TestEncodeWithRecovery(t *testing.T) {
+	tcs := []struct {
+		name          string
+		origCount     int
+		recoveryCount int
+		shareSize     int
+		wantErr       error
+	}{
+		{"32+8", 32, 8, 64, nil},
+		{"64+16", 64, 16, 128, nil},
+		{"one share, one parity", 1, 1, 64, nil},
+		{"single parity", 5, 1, 64, nil},
+		{"no parity", 4, 0, 64, ErrInvalidCounts},
+		{"more parity than data", 4, 5, 64, ErrInvalidCounts},
+		{"too many shares", 40000, 30000, 64, ErrTooMuchData},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			originalData := make([][]byte, tc.origCount)
+			for i := 0; i < tc.origCount; i++ {
+				originalData[i] = make([]byte, tc.shareSize)
+				checkedRandBytes(originalData[i])
+			}
+			encoded, err := EncodeWithRecovery(originalData, tc.recoveryCount)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), err)
+				var leoErr *LeopardError
+				require.True(t, errors.As(err, &leoErr))
+				assert.Equal(t, OpEncode, leoErr.Op)
+				assert.Equal(t, tc.recoveryCount, leoErr.RecoveryCount)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.recoveryCount, len(encoded))
+			for _, e := range encoded {
+				assert.Equal(t, tc.shareSize, len(e))
+			}
+		})
+	}
+}
+
 func TestEncodeRecoverRoundtripSimple(t *testing.T) {
 	const originalCount = 1024
 	const bufferBytes = 6400
@@ -157,6 +198,391 @@ func TestEncodeDecodeRoundtrips(t *testing.T) {
 	}
 }
 
+func TestEncodeDecodeRoundtripsWithRecovery(t *testing.T) {
+	tcs := []struct {
+		name          string
+		origCount     int
+		recoveryCount int
+		shareSize     int
+	}{
+		{"one share", 1, 1, 64},
+		{"single parity", 7, 1, 64},
+		{"5+3", 5, 3, 64},
+		{"32+8", 32, 8, 128},
+		{"64+16", 64, 16, 64},
+		{"300+100 (ff16)", 300, 100, 64},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			originalData := make([][]byte, tc.origCount)
+			for i := 0; i < tc.origCount; i++ {
+				originalData[i] = make([]byte, tc.shareSize)
+				checkedRandBytes(originalData[i])
+			}
+			origCopy := deepCopy(originalData)
+
+			encoded, err := EncodeWithRecovery(originalData, tc.recoveryCount)
+			require.NoError(t, err)
+			origEnc := deepCopy(encoded)
+
+			// lose exactly recoveryCount shares (original or parity):
+			for _, loseIdx := range rand.Perm(tc.origCount + tc.recoveryCount)[:tc.recoveryCount] {
+				if loseIdx < tc.origCount {
+					originalData[loseIdx] = nil
+				} else {
+					encoded[loseIdx-tc.origCount] = nil
+				}
+			}
+
+			dec, err := Decode(originalData, encoded)
+			require.NoError(t, err)
+			require.Equal(t, tc.origCount+tc.recoveryCount, len(dec))
+			assert.Equal(t, origCopy, dec[:tc.origCount])
+			assert.Equal(t, origEnc, dec[tc.origCount:])
+		})
+	}
+}
+
+func TestEncodeIntoRecoverInto(t *testing.T) {
+	const originalCount = 32
+	const recoveryCount = 8
+	const bufferBytes = 128
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	origCopy := deepCopy(originalData)
+
+	parity := make([][]byte, recoveryCount)
+	for i := range parity {
+		parity[i] = make([]byte, bufferBytes)
+	}
+	require.NoError(t, EncodeInto(originalData, parity))
+	want, err := EncodeWithRecovery(originalData, recoveryCount)
+	require.NoError(t, err)
+	assert.Equal(t, want, parity)
+
+	// wrongly shaped parity buffers are rejected:
+	assert.True(t, errors.Is(EncodeInto(originalData, [][]byte{make([]byte, 64)}), ErrInvalidInput))
+
+	for _, loseIdx := range rand.Perm(originalCount + recoveryCount)[:recoveryCount] {
+		if loseIdx < originalCount {
+			originalData[loseIdx] = nil
+		} else {
+			parity[loseIdx-originalCount] = nil
+		}
+	}
+	out := make([][]byte, originalCount+recoveryCount)
+	for i := range out {
+		out[i] = make([]byte, bufferBytes)
+	}
+	outPtrs := make([]*byte, len(out))
+	for i := range out {
+		outPtrs[i] = &out[i][0]
+	}
+	require.NoError(t, RecoverInto(originalData, parity, out))
+	assert.Equal(t, origCopy, out[:originalCount])
+	assert.Equal(t, want, out[originalCount:])
+	// the caller's buffers were filled and not replaced:
+	for i := range out {
+		assert.True(t, outPtrs[i] == &out[i][0])
+	}
+
+	assert.True(t, errors.Is(RecoverInto(originalData, parity, out[1:]), ErrInvalidCounts))
+}
+
+func TestReconstruct(t *testing.T) {
+	const originalCount = 16
+	const recoveryCount = 8
+	const bufferBytes = 64
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount)
+	require.NoError(t, err)
+	want := deepCopy(append(originalData, encoded...))
+
+	shards := append(deepCopy(originalData), deepCopy(encoded)...)
+	lost := rand.Perm(originalCount + recoveryCount)[:recoveryCount]
+	for _, i := range lost {
+		shards[i] = nil
+	}
+	present := make(map[int]*byte)
+	for i, s := range shards {
+		if s != nil {
+			present[i] = &s[0]
+		}
+	}
+
+	require.NoError(t, Reconstruct(shards, originalCount))
+	assert.Equal(t, want, shards)
+	// present shares are still the very same slices:
+	for i, p := range present {
+		assert.True(t, p == &shards[i][0])
+	}
+
+	// on failure the shards are left as they were:
+	for _, i := range rand.Perm(originalCount + recoveryCount)[:recoveryCount+1] {
+		shards[i] = nil
+	}
+	before := append([][]byte(nil), shards...)
+	assert.True(t, errors.Is(Reconstruct(shards, originalCount), ErrNeedMoreData))
+	assert.Equal(t, before, shards)
+}
+
+func TestReconstructData(t *testing.T) {
+	const originalCount = 16
+	const recoveryCount = 8
+	const bufferBytes = 64
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount)
+	require.NoError(t, err)
+
+	shards := append(deepCopy(originalData), deepCopy(encoded)...)
+	// lose some original and some recovery shares:
+	for _, i := range []int{0, 3, 5, 15, originalCount, originalCount + 7} {
+		shards[i] = nil
+	}
+
+	require.NoError(t, ReconstructData(shards, originalCount))
+	assert.Equal(t, originalData, shards[:originalCount])
+	assert.Nil(t, shards[originalCount])
+	assert.Nil(t, shards[originalCount+7])
+	assert.Equal(t, encoded[1:7], shards[originalCount+1:originalCount+7])
+}
+
+func TestVerify(t *testing.T) {
+	const originalCount = 32
+	const recoveryCount = 8
+	const bufferBytes = 64
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount)
+	require.NoError(t, err)
+
+	ok, err := Verify(originalData, encoded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	encoded[2][0] ^= 1
+	encoded[5][bufferBytes-1] ^= 0x80
+	ok, err = Verify(originalData, encoded)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	mismatches, err := ParityMismatches(originalData, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 5}, mismatches)
+
+	// changing the data invalidates (almost) all parity shares:
+	encoded[2][0] ^= 1
+	encoded[5][bufferBytes-1] ^= 0x80
+	originalData[7][3] ^= 1
+	ok, err = Verify(originalData, encoded)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	encoded[1] = nil
+	_, err = Verify(originalData, encoded)
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+}
+
+func TestEncodeDecodeWithPadding(t *testing.T) {
+	const originalCount = 16
+	const recoveryCount = 4
+	const bufferBytes = 478
+
+	originalData := make([][]byte, originalCount)
+	padded := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+		padded[i] = make([]byte, 512)
+		copy(padded[i], originalData[i])
+	}
+	origCopy := deepCopy(originalData)
+
+	_, err := EncodeWithRecovery(originalData, recoveryCount)
+	assert.Equal(t, ErrInvalidSize, err)
+
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount, WithPadding())
+	require.NoError(t, err)
+	require.Equal(t, recoveryCount, len(encoded))
+	// identical to encoding the manually padded data:
+	want, err := EncodeWithRecovery(padded, recoveryCount)
+	require.NoError(t, err)
+	for i := range encoded {
+		assert.Equal(t, bufferBytes, len(encoded[i]))
+		assert.Equal(t, want[i][:bufferBytes], encoded[i])
+		assert.Equal(t, make([]byte, 512-bufferBytes), want[i][bufferBytes:])
+	}
+	origEnc := deepCopy(encoded)
+
+	for _, i := range []int{1, 2, 9} {
+		originalData[i] = nil
+	}
+	encoded[3] = nil
+	dec, err := Decode(originalData, encoded, WithPadding())
+	require.NoError(t, err)
+	assert.Equal(t, origCopy, dec[:originalCount])
+	assert.Equal(t, origEnc, dec[originalCount:])
+}
+
+func TestPaddingWideSymbols(t *testing.T) {
+	// 200+100 shares are encoded in GF(2^16):
+	const originalCount = 200
+	const recoveryCount = 100
+	const bufferBytes = 100
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount, WithPadding())
+	require.NoError(t, err)
+	want := append(deepCopy(originalData), deepCopy(encoded)...)
+	ok, err := Verify(originalData, encoded, WithPadding())
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	shards := append(deepCopy(originalData), deepCopy(encoded)...)
+	for _, i := range rand.Perm(len(shards))[:recoveryCount] {
+		shards[i] = nil
+	}
+	require.NoError(t, Reconstruct(shards, originalCount, WithPadding()))
+	assert.Equal(t, want, shards)
+
+	// odd sizes can't be trimmed in GF(2^16), but in GF(2^8):
+	_, err = NewCodec(originalCount, recoveryCount, 101, WithPadding())
+	assert.Equal(t, ErrInvalidSize, err)
+	c, err := NewCodec(100, 28, 101, WithPadding())
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+}
+
+func TestReconstructAvailable(t *testing.T) {
+	const originalCount = 16
+	const recoveryCount = 8
+	const bufferBytes = 64
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, bufferBytes)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount)
+	require.NoError(t, err)
+	want := append(deepCopy(originalData), deepCopy(encoded)...)
+
+	shards := append(deepCopy(originalData), deepCopy(encoded)...)
+	avail := NewAvailability(originalCount + recoveryCount)
+	for i := range shards {
+		avail.Set(i)
+	}
+	// a missing share with a buffer of the right size is filled in place,
+	// one with the wrong size is replaced:
+	avail.Unset(2)
+	shards[2] = make([]byte, bufferBytes)
+	reused := &shards[2][0]
+	avail.Unset(originalCount + 1)
+	shards[originalCount+1] = []byte{1, 2, 3}
+
+	// a present but malformed share is rejected rather than treated as missing:
+	shards[5] = shards[5][:0]
+	err = ReconstructAvailable(shards, originalCount, avail)
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+	shards[5] = shards[5][:bufferBytes]
+
+	// the share size is taken from the majority of the present shares, so a
+	// malformed first share is the one reported:
+	shards[0] = shards[0][:bufferBytes-1]
+	err = ReconstructAvailable(shards, originalCount, avail)
+	var lerr *LeopardError
+	require.True(t, errors.As(err, &lerr))
+	assert.Equal(t, 0, lerr.Shard)
+	shards[0] = shards[0][:bufferBytes]
+
+	require.NoError(t, ReconstructAvailable(shards, originalCount, avail))
+	assert.Equal(t, want, shards)
+	assert.True(t, reused == &shards[2][0])
+	assert.Equal(t, originalCount+recoveryCount, avail.Count())
+
+	// without an Availability, nil entries are missing:
+	shards[0], shards[originalCount] = nil, nil
+	require.NoError(t, ReconstructAvailable(shards, originalCount, nil))
+	assert.Equal(t, want, shards)
+}
+
+func TestRecoverNeedMoreData(t *testing.T) {
+	const originalCount = 16
+	const recoveryCount = 4
+
+	originalData := make([][]byte, originalCount)
+	for i := 0; i < originalCount; i++ {
+		originalData[i] = make([]byte, 64)
+		checkedRandBytes(originalData[i])
+	}
+	encoded, err := EncodeWithRecovery(originalData, recoveryCount)
+	require.NoError(t, err)
+
+	for i := 0; i <= recoveryCount; i++ {
+		originalData[i] = nil
+	}
+	_, err = Recover(originalData, encoded)
+	assert.True(t, errors.Is(err, ErrNeedMoreData))
+
+	var leoErr *LeopardError
+	require.True(t, errors.As(err, &leoErr))
+	assert.Equal(t, LeopardNeedmoredata, leoErr.Result)
+	assert.Equal(t, OpDecode, leoErr.Op)
+	assert.Equal(t, originalCount, leoErr.OrigCount)
+	assert.Equal(t, recoveryCount, leoErr.RecoveryCount)
+	assert.Equal(t, 64, leoErr.BufferBytes)
+	assert.Equal(t, -1, leoErr.Shard)
+}
+
+func TestLeopardError(t *testing.T) {
+	c, err := NewCodec(4, 2, 64)
+	require.NoError(t, err)
+	defer c.Close()
+
+	data := [][]byte{make([]byte, 64), make([]byte, 64), make([]byte, 128), make([]byte, 64)}
+	_, err = c.Encode(data)
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+	var leoErr *LeopardError
+	require.True(t, errors.As(err, &leoErr))
+	assert.Equal(t, OpEncode, leoErr.Op)
+	assert.Equal(t, 2, leoErr.Shard)
+	assert.Contains(t, err.Error(), "share 2")
+
+	// offsets of recovery shares are relative to (orig || recovery):
+	data[2] = data[2][:64]
+	_, err = c.Recover(data, [][]byte{nil, make([]byte, 65)})
+	require.True(t, errors.As(err, &leoErr))
+	assert.Equal(t, OpDecode, leoErr.Op)
+	assert.Equal(t, 5, leoErr.Shard)
+
+	// unknown result codes neither panic nor match any sentinel:
+	unknown := &LeopardError{Result: Leopardresult(-42), Op: OpEncode, Shard: -1}
+	assert.NotEmpty(t, unknown.Error())
+	assert.Nil(t, unknown.Unwrap())
+}
+
 func TestFF8EncodeRecoverRoundtrip(t *testing.T) {
 	const originalCount = 128
 	const lossCount = 128 // lose exactly originalCount of total data
@@ -212,7 +638,7 @@ func TestEncodeDecodeRoundtripRandomized(t *testing.T) {
 	for i := 0; i < rounds; i++ {
 		originalCount := rand.Intn(maxOrig-1) + 1
 		bufferBytes := (rand.Intn(maxBufferBytes) + 17) * 64
-		decodeWorkCount := leopard.LeoDecodeWorkCount(uint32(originalCount), uint32(originalCount))
+		decodeWorkCount := goleo.DecodeWorkCount(originalCount, originalCount)
 		lossCount := rand.Int31n(int32(decodeWorkCount)) + 1%int32(originalCount)
 
 		originalData := make([][]byte, originalCount)
@@ -245,34 +671,6 @@ func TestEncodeDecodeRoundtripRandomized(t *testing.T) {
 	}
 }
 
-func TestMemRoundTrip(t *testing.T) {
-	t.Skip("Skip testing private memory helper: freeAll")
-	const originalCount = 128
-	const bufferBytes = 640
-
-	originalData := make([][]byte, originalCount)
-	for i := 0; i < originalCount; i++ {
-		originalData[i] = make([]byte, bufferBytes)
-		checkedRandBytes(originalData[i])
-	}
-	ptrs := mockScopeFunc1(originalData)
-	result := mockScopeFunc2(originalCount, ptrs, bufferBytes)
-	// freeAll pointers and see if we run into any problem:
-	freeAll(ptrs)
-	assert.EqualValues(t, originalData, result)
-}
-
-func mockScopeFunc2(originalCount int, ptrs []unsafe.Pointer, bufferBytes int) [][]byte {
-	result := make([][]byte, originalCount)
-	toGoByte(ptrs, result, bufferBytes)
-	return result
-}
-
-func mockScopeFunc1(originalData [][]byte) []unsafe.Pointer {
-	ptrs := copyToCmallocedPtrs(originalData)
-	return ptrs
-}
-
 // Helper functions for checking we can recover original data without bytes.Equal():
 func checkedRandBytes(p []byte) {
 	if len(p) <= md5.Size {